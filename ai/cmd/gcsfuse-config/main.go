@@ -0,0 +1,176 @@
+// Command gcsfuse-config generates a GCSFuse config for a workload and prints
+// it, or saves it to the file given with -output. It is a thin wrapper around
+// ai.GenerateGCSFuseConfig.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	genai "github.com/google/generative-ai-go/genai"
+
+	"go-core/ai"
+)
+
+func main() {
+	timeout := flag.Duration("timeout", ai.DefaultTimeout, "Overall deadline for generating the config")
+	promptTemplatePath := flag.String("prompt-template", "", "Path to a text/template file for the prompt; uses the built-in prompt if empty")
+	model := flag.String("model", ai.DefaultModel, "Gemini model to generate the config with")
+	temperature := flag.Float64("temperature", -1, "Sampling temperature; uses the model default if negative")
+	maxOutputTokens := flag.Int("max-output-tokens", 0, "Limit on the generated tokens; uses the model default if zero")
+	maxPromptTokens := flag.Int("max-prompt-tokens", 0, "Token budget of the prompt, checked before sending it; no limit if zero")
+	samplesDir := flag.String("samples", "samples", "Folder of sample configurations")
+	include := flag.String("include", "", "Comma-separated glob patterns of the sample files to use; all if empty")
+	exclude := flag.String("exclude", "", "Comma-separated glob patterns of sample files and folders to skip")
+	maxSampleSize := flag.Int64("max-sample-size", 0, "Bytes of each sample file to include at most; no limit if zero")
+	workloadPath := flag.String("workload", "workload_details.txt", "File describing the workload")
+	tuningGuidePath := flag.String("tuning-guide", "GCSFuseTuningGuideFinal.pdf", "Tuning guide PDF to upload")
+	tuningGuideURI := flag.String("tuning-guide-uri", "", "URI of an already uploaded tuning guide PDF, used instead of -tuning-guide")
+	uploadCachePath := flag.String("upload-cache", "upload_cache.json", "JSON file remembering uploaded files between runs; in memory only if empty")
+	stream := flag.Bool("stream", false, "Print the response to stderr as it arrives")
+	dryRun := flag.Bool("dry-run", false, "Print the assembled prompt instead of generating a config")
+	outputFile := flag.String("output", "-", "File to save the generated config to; standard output if \"-\"")
+	flag.Parse()
+
+	ctx := context.Background()
+
+	// A dry run only prints the prompt, so it needs neither a client nor an
+	// API key.
+	var client *genai.Client
+	if !*dryRun {
+		var err error
+		if client, err = ai.NewClient(ctx); err != nil {
+			log.Fatal(err)
+		}
+		defer client.Close()
+	}
+
+	// Read all the sample config files and create a single string with all the
+	// text content; other samples are uploaded below
+	samples, fileErrs, err := ai.ConsolidateSamples(ctx, *samplesDir, ai.ConsolidateOptions{
+		Include:     splitList(*include),
+		Exclude:     splitList(*exclude),
+		MaxFileSize: *maxSampleSize,
+	})
+	if err != nil {
+		log.Fatalf("Error consolidating text files: %v", err)
+	}
+	for _, fileErr := range fileErrs {
+		log.Printf("Warning: Could not read file %s: %v", fileErr.Path, fileErr.Err)
+	}
+
+	// The tuning guide is a PDF, uploaded once and then reused from the cache,
+	// like the samples that are not text. A dry run names the files instead.
+	uploadCache, err := ai.NewFileCache(*uploadCachePath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	upload := func(path, mimeType string) genai.FileData {
+		if *dryRun {
+			return genai.FileData{MIMEType: mimeType, URI: "not-uploaded:" + path}
+		}
+		data, err := uploadCache.UploadFile(ctx, path, client)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return data
+	}
+	tuningGuideData := genai.FileData{
+		MIMEType: "application/pdf",
+		URI:      *tuningGuideURI,
+	}
+	if *tuningGuideURI == "" {
+		tuningGuideData = upload(*tuningGuidePath, "application/pdf")
+	}
+	var sampleFiles []genai.FileData
+	for _, file := range samples.Files {
+		sampleFiles = append(sampleFiles, upload(file.Path, file.MIMEType))
+	}
+
+	// Read the workload details. We will determine the gcsfuse config based on these details.
+	workloadData, err := os.ReadFile(*workloadPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	promptTemplate, err := ai.LoadPromptTemplate(*promptTemplatePath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	opts := ai.GenerateOptions{
+		Model:           *model,
+		Timeout:         *timeout,
+		PromptTemplate:  promptTemplate,
+		TuningGuide:     &tuningGuideData,
+		SampleFiles:     sampleFiles,
+		MaxPromptTokens: *maxPromptTokens,
+		DryRun:          *dryRun,
+	}
+	if *temperature >= 0 {
+		opts.Temperature = genai.Ptr(float32(*temperature))
+	}
+	if *maxOutputTokens > 0 {
+		opts.MaxOutputTokens = genai.Ptr(int32(*maxOutputTokens))
+	}
+	var config string
+	var genErr error
+	if *stream {
+		config, genErr = ai.GenerateGCSFuseConfigStream(ctx, client, workloadData, []byte(samples.Text), os.Stderr, opts)
+	} else {
+		config, genErr = ai.GenerateGCSFuseConfig(ctx, client, workloadData, []byte(samples.Text), opts)
+	}
+	if genErr != nil && config == "" {
+		log.Fatal(genErr)
+	}
+	if *dryRun {
+		fmt.Print(config) // The prompt; there is no config to save.
+		return
+	}
+	if errors.Is(genErr, ai.ErrInvalidYAML) {
+		fmt.Println(config) // Show the raw response rather than saving it.
+		log.Fatal(genErr)
+	}
+
+	// Save the generated config, even if it failed schema validation.
+	if *outputFile == "-" {
+		if err := ai.WriteConfig(os.Stdout, config); err != nil {
+			log.Fatal(err)
+		}
+	} else if err := saveConfig(*outputFile, config); err != nil {
+		log.Printf("Error saving generated config: %v\n", err)
+		fmt.Println(config) // Print to console as fallback
+	} else {
+		fmt.Printf("Generated config saved to: %s\n", *outputFile)
+	}
+	if genErr != nil {
+		log.Fatal(genErr)
+	}
+}
+
+// saveConfig writes config to the file at path, replacing it.
+func saveConfig(path string, config string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := ai.WriteConfig(f, config); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// splitList splits a comma-separated flag value, returning nil for an empty
+// one.
+func splitList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
@@ -0,0 +1,149 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	genai "github.com/google/generative-ai-go/genai"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// cachedFile is a FileCache entry for one uploaded file.
+type cachedFile struct {
+	Name     string // Resource name, e.g. "files/abc", used to check the file is still active.
+	MIMEType string
+	URI      string
+	Uploaded time.Time
+}
+
+// FileCache remembers the files uploaded through it, keyed by the SHA-256 of
+// their content, so running again with the same file skips the upload and the
+// wait for processing. Entries are checked with the API before use and
+// dropped once the file is gone or no longer active, e.g. after it expired;
+// other errors while checking, such as a cancelled context, are returned and
+// keep the entry. The cache is kept in a JSON file, or only in memory if no
+// path is given. It is safe for concurrent use.
+type FileCache struct {
+	path string // JSON file the entries are saved to; empty for memory only.
+
+	mu      sync.Mutex
+	entries map[string]cachedFile // By hex SHA-256 of the file content.
+}
+
+// NewFileCache returns a cache saved at path, loading the entries already
+// stored there. A missing file is an empty cache. If path is empty the cache
+// lives in memory only.
+func NewFileCache(path string) (*FileCache, error) {
+	c := &FileCache{path: path, entries: make(map[string]cachedFile)}
+	if path == "" {
+		return c, nil
+	}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading file cache: %w", err)
+	}
+	if err := json.Unmarshal(content, &c.entries); err != nil {
+		return nil, fmt.Errorf("parsing file cache %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// UploadFile is UploadFile from the package, but reuses an earlier upload of
+// the same content if it is still active.
+func (c *FileCache) UploadFile(ctx context.Context, fileName string, client *genai.Client) (genai.FileData, error) {
+	return c.upload(ctx, fileName, client)
+}
+
+// upload implements UploadFile for any fileService.
+func (c *FileCache) upload(ctx context.Context, fileName string, files fileService) (genai.FileData, error) {
+	key, err := hashFile(fileName)
+	if err != nil {
+		return genai.FileData{}, err
+	}
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok {
+		file, err := files.GetFile(ctx, entry.Name)
+		if err == nil && file.State == genai.FileStateActive {
+			return fileData(file), nil
+		}
+		if err != nil && !fileGone(err) {
+			return genai.FileData{}, fmt.Errorf("checking cached upload %s of %s: %w", entry.Name, fileName, err)
+		}
+		log.Printf("Cached upload %s of %s is no longer usable, uploading again", entry.Name, fileName)
+		if err := c.update(key, nil); err != nil {
+			return genai.FileData{}, err
+		}
+	}
+
+	file, err := uploadFile(ctx, fileName, files)
+	if err != nil {
+		return genai.FileData{}, err
+	}
+	err = c.update(key, &cachedFile{Name: file.Name, MIMEType: file.MIMEType, URI: file.URI, Uploaded: time.Now()})
+	return fileData(file), err
+}
+
+// fileGone reports whether err from GetFile means the file no longer exists.
+// The API reports files that expired or were deleted as not found, or as
+// permission denied since it cannot tell them from files of other projects.
+func fileGone(err error) bool {
+	switch status.Code(err) {
+	case codes.NotFound, codes.PermissionDenied:
+		return true
+	}
+	return false
+}
+
+// update stores entry under key, or removes key if entry is nil, and saves
+// the cache.
+func (c *FileCache) update(key string, entry *cachedFile) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry != nil {
+		c.entries[key] = *entry
+	} else {
+		delete(c.entries, key)
+	}
+	if c.path == "" {
+		return nil
+	}
+	content, err := json.MarshalIndent(c.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(c.path, content, 0644); err != nil {
+		return fmt.Errorf("saving file cache: %w", err)
+	}
+	return nil
+}
+
+// hashFile returns the hex SHA-256 of the content of fileName.
+func hashFile(fileName string) (string, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("hashing %s: %w", fileName, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,151 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	genai "github.com/google/generative-ai-go/genai"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeFiles is a fileService whose uploads are active right away unless
+// expired says otherwise. GetFile fails for the files in getErr.
+type fakeFiles struct {
+	uploads int
+	expired map[string]bool  // By file name.
+	getErr  map[string]error // By file name.
+}
+
+func (f *fakeFiles) UploadFile(ctx context.Context, name string, r io.Reader, opts *genai.UploadFileOptions) (*genai.File, error) {
+	if _, err := io.ReadAll(r); err != nil {
+		return nil, err
+	}
+	f.uploads++
+	return f.GetFile(ctx, fmt.Sprintf("files/%d", f.uploads))
+}
+
+func (f *fakeFiles) GetFile(ctx context.Context, name string) (*genai.File, error) {
+	if err := f.getErr[name]; err != nil {
+		return nil, err
+	}
+	state := genai.FileStateActive
+	if f.expired[name] {
+		state = genai.FileStateFailed
+	}
+	return &genai.File{Name: name, MIMEType: "application/pdf", URI: "uri/" + name, State: state}, nil
+}
+
+func TestFileCacheSkipsRepeatedUploads(t *testing.T) {
+	dir := t.TempDir()
+	guide := filepath.Join(dir, "guide.pdf")
+	if err := os.WriteFile(guide, []byte("tuning guide"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cachePath := filepath.Join(dir, "cache.json")
+	files := &fakeFiles{expired: make(map[string]bool)}
+
+	cache, err := NewFileCache(cachePath)
+	if err != nil {
+		t.Fatalf("NewFileCache failed: %v", err)
+	}
+	first, err := cache.upload(context.Background(), guide, files)
+	if err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+
+	// A new cache loads the entry saved by the first one.
+	cache, err = NewFileCache(cachePath)
+	if err != nil {
+		t.Fatalf("NewFileCache failed: %v", err)
+	}
+	second, err := cache.upload(context.Background(), guide, files)
+	if err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+	if files.uploads != 1 || second != first {
+		t.Errorf("got %d uploads and %v, want 1 upload reused as %v", files.uploads, second, first)
+	}
+
+	// Expired uploads are replaced.
+	files.expired["files/1"] = true
+	third, err := cache.upload(context.Background(), guide, files)
+	if err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+	if files.uploads != 2 || third.URI != "uri/files/2" {
+		t.Errorf("got %d uploads and %v, want a second upload", files.uploads, third)
+	}
+
+	// Different content is uploaded separately.
+	if err := os.WriteFile(guide, []byte("revised tuning guide"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.upload(context.Background(), guide, files); err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+	if files.uploads != 3 {
+		t.Errorf("got %d uploads, want changed content uploaded again", files.uploads)
+	}
+}
+
+func TestFileCacheCheckErrors(t *testing.T) {
+	guide := filepath.Join(t.TempDir(), "guide.pdf")
+	if err := os.WriteFile(guide, []byte("tuning guide"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	files := &fakeFiles{getErr: make(map[string]error)}
+	cache, err := NewFileCache("")
+	if err != nil {
+		t.Fatalf("NewFileCache failed: %v", err)
+	}
+	if _, err := cache.upload(context.Background(), guide, files); err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+
+	// Errors that say nothing about the file keep the entry.
+	for _, getErr := range []error{context.Canceled, status.Error(codes.Unavailable, "try again")} {
+		files.getErr["files/1"] = getErr
+		if _, err := cache.upload(context.Background(), guide, files); !errors.Is(err, getErr) {
+			t.Errorf("upload with GetFile failing with %v: error = %v, want it wrapped", getErr, err)
+		}
+	}
+	if files.uploads != 1 {
+		t.Errorf("got %d uploads after failed checks, want 1", files.uploads)
+	}
+
+	// A file that is gone is uploaded again.
+	files.getErr["files/1"] = status.Error(codes.NotFound, "no such file")
+	data, err := cache.upload(context.Background(), guide, files)
+	if err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+	if files.uploads != 2 || data.URI != "uri/files/2" {
+		t.Errorf("got %d uploads and %v, want the missing file uploaded again", files.uploads, data)
+	}
+}
+
+func TestFileCacheInMemory(t *testing.T) {
+	guide := filepath.Join(t.TempDir(), "guide.pdf")
+	if err := os.WriteFile(guide, []byte("tuning guide"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	files := &fakeFiles{}
+	cache, err := NewFileCache("")
+	if err != nil {
+		t.Fatalf("NewFileCache failed: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := cache.upload(context.Background(), guide, files); err != nil {
+			t.Fatalf("upload failed: %v", err)
+		}
+	}
+	if files.uploads != 1 {
+		t.Errorf("got %d uploads, want 1", files.uploads)
+	}
+}
@@ -0,0 +1,499 @@
+// Package ai generates GCSFuse configs for a workload with a Gemini model,
+// using sample configs and the GCSFuse tuning guide as context.
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	genai "github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/option"
+)
+
+// DefaultModel is the Gemini model used unless GenerateOptions.Model is set.
+const DefaultModel = "gemini-2.5-pro"
+
+// DefaultTimeout is the overall deadline for generating a config unless
+// GenerateOptions.Timeout is set.
+const DefaultTimeout = 5 * time.Minute
+
+// filePollInterval is how often UploadFile checks whether an upload is ready.
+const filePollInterval = 5 * time.Second
+
+// UploadFile uploads fileName, e.g. the tuning guide PDF, and waits until the
+// API has processed it, so the returned FileData can be used in a prompt. See
+// FileCache to avoid uploading the same file again on every run.
+func UploadFile(ctx context.Context, fileName string, client *genai.Client) (genai.FileData, error) {
+	file, err := uploadFile(ctx, fileName, client)
+	if err != nil {
+		return genai.FileData{}, err
+	}
+	return fileData(file), nil
+}
+
+// fileService is the part of *genai.Client used to upload files, so tests can
+// substitute a fake.
+type fileService interface {
+	UploadFile(ctx context.Context, name string, r io.Reader, opts *genai.UploadFileOptions) (*genai.File, error)
+	GetFile(ctx context.Context, name string) (*genai.File, error)
+}
+
+// uploadFile implements UploadFile, returning the active file.
+func uploadFile(ctx context.Context, fileName string, files fileService) (*genai.File, error) {
+	f, err := os.OpenFile(fileName, os.O_RDONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	file, err := files.UploadFile(ctx, "", f, nil)
+	if err != nil {
+		return nil, fmt.Errorf("uploading %s: %w", fileName, err)
+	}
+	log.Printf("URI for file %s with mimeType %s is %s", fileName, file.MIMEType, file.URI)
+
+	// --- POLLING LOGIC ---
+	// The file is not ready to be used until its state is ACTIVE.
+	// We must poll the API until the processing is complete.
+	for {
+		// Get the latest status of the file.
+		f, err := files.GetFile(ctx, file.Name)
+		if err != nil {
+			return nil, fmt.Errorf("getting file status for %s: %w", file.Name, err)
+		}
+
+		// If the file is active, we can stop polling and use it.
+		if f.State == genai.FileStateActive {
+			log.Printf("File '%s' is now active. URI: %s", f.DisplayName, f.URI)
+			return f, nil
+		}
+
+		// If the file processing failed, we can't continue.
+		if f.State == genai.FileStateFailed {
+			return nil, fmt.Errorf("file processing failed for %s. State: %s", f.DisplayName, f.State)
+		}
+
+		log.Printf("File '%s' is still processing, waiting %v...", f.DisplayName, filePollInterval)
+		select {
+		case <-time.After(filePollInterval): // Wait before checking again.
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// fileData returns the reference to file used in prompts.
+func fileData(file *genai.File) genai.FileData {
+	return genai.FileData{
+		MIMEType: file.MIMEType,
+		URI:      file.URI,
+	}
+}
+
+// FileError records a file that ConsolidateTextFiles could not read.
+type FileError struct {
+	Path string
+	Err  error
+}
+
+func (e FileError) Error() string {
+	return fmt.Sprintf("reading %s: %v", e.Path, e.Err)
+}
+
+func (e FileError) Unwrap() error {
+	return e.Err
+}
+
+// ConsolidateOptions restricts which files ConsolidateTextFiles inlines. The
+// zero value inlines every file in full.
+type ConsolidateOptions struct {
+	// Include lists glob patterns, as for filepath.Match, of the files to
+	// inline; all files if empty. Exclude lists patterns of files and folders
+	// to skip, and wins over Include. Patterns without a path separator match
+	// the base name, others the path relative to the folder.
+	Include []string
+	Exclude []string
+
+	// MaxFileSize caps the bytes inlined per file; larger files are cut off
+	// with a truncation marker. Zero means no limit.
+	MaxFileSize int64
+}
+
+// matches reports whether rel, a slash-separated path relative to the folder,
+// matches any of patterns.
+func matches(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		name := rel
+		if !strings.Contains(pattern, "/") {
+			name = path.Base(rel)
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// SampleFile is a sample that is not text, such as a PDF or an image. It is
+// not inlined but should be uploaded, see UploadFile, and passed to the model
+// as a file through GenerateOptions.SampleFiles.
+type SampleFile struct {
+	Path     string
+	MIMEType string
+}
+
+// Samples is the content of a samples folder as gathered by
+// ConsolidateSamples.
+type Samples struct {
+	// Text concatenates the text files, as returned by ConsolidateTextFiles.
+	Text string
+
+	// Files lists the other files, in walk order.
+	Files []SampleFile
+}
+
+// ConsolidateTextFiles concatenates the text files under folderPath selected
+// by opts, each between START and END markers naming it, for use as the
+// samples passed to GenerateGCSFuseConfig. Files that are not text are left
+// out; use ConsolidateSamples to get them too. Files that cannot be read are
+// left out of the content and reported as FileErrors, so the caller can
+// decide whether partial content is acceptable. The error is set when
+// folderPath itself cannot be walked, e.g. because it does not exist, or a
+// pattern is malformed. The walk stops early with an error wrapping ctx.Err()
+// once ctx is done.
+func ConsolidateTextFiles(ctx context.Context, folderPath string, opts ConsolidateOptions) (string, []FileError, error) {
+	samples, fileErrs, err := ConsolidateSamples(ctx, folderPath, opts)
+	return samples.Text, fileErrs, err
+}
+
+// ConsolidateSamples gathers the files under folderPath selected by opts like
+// ConsolidateTextFiles, and also lists the files that are not text instead of
+// dropping them. A file counts as text if its content is detected as text/*
+// by http.DetectContentType; the MIME type of other files is taken from
+// their extension if known, or else from the detected content type.
+func ConsolidateSamples(ctx context.Context, folderPath string, opts ConsolidateOptions) (Samples, []FileError, error) {
+	return consolidateFiles(ctx, os.DirFS(folderPath), folderPath, opts)
+}
+
+// consolidateFiles implements ConsolidateSamples on fsys, holding the
+// contents of folderPath, so tests can substitute a slow file system.
+func consolidateFiles(ctx context.Context, fsys fs.FS, folderPath string, opts ConsolidateOptions) (Samples, []FileError, error) {
+	for _, pattern := range append(opts.Include, opts.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return Samples{}, nil, fmt.Errorf("bad pattern %q: %w", pattern, err)
+		}
+	}
+
+	var builder strings.Builder
+	var files []SampleFile
+	var fileErrs []FileError
+	err := fs.WalkDir(fsys, ".", func(rel string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if rel != "." && matches(opts.Exclude, rel) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || (len(opts.Include) > 0 && !matches(opts.Include, rel)) {
+			return nil
+		}
+
+		path := filepath.Join(folderPath, filepath.FromSlash(rel))
+		mimeType, isText, sniffErr := sniffContentType(fsys, rel)
+		if sniffErr != nil {
+			fileErrs = append(fileErrs, FileError{Path: path, Err: sniffErr})
+			return nil
+		}
+		if !isText {
+			files = append(files, SampleFile{Path: path, MIMEType: mimeType})
+			return nil
+		}
+
+		content, truncated, readErr := readFilePrefix(fsys, rel, opts.MaxFileSize)
+		if readErr != nil {
+			fileErrs = append(fileErrs, FileError{Path: path, Err: readErr})
+			return nil
+		}
+		builder.WriteString(fmt.Sprintf("\n--- START OF FILE: %s ---\n", path))
+		builder.Write(content)
+		if truncated {
+			builder.WriteString(fmt.Sprintf("\n--- TRUNCATED: %s is larger than %d bytes ---", path, opts.MaxFileSize))
+		}
+		builder.WriteString(fmt.Sprintf("\n--- END OF FILE: %s ---\n", path))
+		return nil
+	})
+
+	if err != nil {
+		return Samples{}, fileErrs, fmt.Errorf("walking %s: %w", folderPath, err)
+	}
+
+	return Samples{Text: builder.String(), Files: files}, fileErrs, nil
+}
+
+// sniffLen is how much of a file http.DetectContentType looks at.
+const sniffLen = 512
+
+// sniffContentType reports whether the file name in fsys is text and, if it
+// is not, its MIME type.
+func sniffContentType(fsys fs.FS, name string) (string, bool, error) {
+	head, _, err := readFilePrefix(fsys, name, sniffLen)
+	if err != nil {
+		return "", false, err
+	}
+	detected := http.DetectContentType(head)
+	if strings.HasPrefix(detected, "text/") {
+		return "", true, nil
+	}
+	if byExt := mime.TypeByExtension(path.Ext(name)); byExt != "" {
+		return byExt, false, nil
+	}
+	return detected, false, nil
+}
+
+// readFilePrefix reads the file name in fsys, or only its first limit bytes
+// if limit is positive, and reports whether anything was left out.
+func readFilePrefix(fsys fs.FS, name string, limit int64) ([]byte, bool, error) {
+	if limit <= 0 {
+		content, err := fs.ReadFile(fsys, name)
+		return content, false, err
+	}
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+	// Read one byte past the limit to tell whether the file is larger.
+	content, err := io.ReadAll(io.LimitReader(f, limit+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if int64(len(content)) > limit {
+		return content[:limit], true, nil
+	}
+	return content, false, nil
+}
+
+// contentGenerator is the part of *genai.GenerativeModel used to generate the
+// config, so tests can substitute a fake model.
+type contentGenerator interface {
+	GenerateContent(ctx context.Context, parts ...genai.Part) (*genai.GenerateContentResponse, error)
+}
+
+// generateWithDeadline calls model.GenerateContent and gives up once timeout
+// has passed, returning context.DeadlineExceeded even if the model ignores
+// the context.
+func generateWithDeadline(ctx context.Context, model contentGenerator, timeout time.Duration, parts ...genai.Part) (*genai.GenerateContentResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type result struct {
+		resp *genai.GenerateContentResponse
+		err  error
+	}
+	resultCh := make(chan result, 1)
+	go func() {
+		resp, err := model.GenerateContent(ctx, parts...)
+		resultCh <- result{resp, err}
+	}()
+
+	select {
+	case r := <-resultCh:
+		return r.resp, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// NewClient creates a Gemini client using the API key in the GEMINI_API_KEY
+// environment variable. The caller must Close it.
+func NewClient(ctx context.Context) (*genai.Client, error) {
+	// Access your API key from the environment variable.
+	apiKey := os.Getenv("GEMINI_API_KEY")
+	if apiKey == "" {
+		return nil, errors.New("GEMINI_API_KEY environment variable not set")
+	}
+
+	// Initialize the client.
+	return genai.NewClient(ctx, option.WithAPIKey(apiKey))
+}
+
+// GenerateOptions tunes GenerateGCSFuseConfig. The zero value uses the
+// defaults.
+type GenerateOptions struct {
+	Model          string               // Gemini model name; DefaultModel if empty or blank.
+	Timeout        time.Duration        // Overall deadline; DefaultTimeout if zero.
+	PromptTemplate string               // text/template source, see LoadPromptTemplate; the built-in prompt if empty.
+	TuningGuide    *genai.FileData      // Uploaded tuning guide added to the prompt, see UploadFile; none if nil.
+	SampleFiles    []genai.FileData     // Uploaded samples that are not text, see ConsolidateSamples.
+	OnWarning      func(warning string) // Receives validation warnings; they are logged if nil.
+
+	// MaxPromptTokens is the token budget of the prompt; a larger prompt fails
+	// with ErrPromptTooLarge before it is sent. Zero means no budget.
+	MaxPromptTokens int
+
+	// DryRun skips the model: the assembled prompt, rendered by FormatPrompt,
+	// is returned in place of the config. The client is not used and may be
+	// nil. Useful to debug prompts for free.
+	DryRun bool
+
+	// Generation parameters; nil leaves the model's default. A low Temperature
+	// keeps the generated config close to the samples.
+	Temperature     *float32
+	MaxOutputTokens *int32
+}
+
+// GenerateGCSFuseConfig asks the model for a GCSFuse config suited to the
+// workload described by workloadYAML, with samples, e.g. the output of
+// ConsolidateTextFiles, as examples. It returns the generated YAML, without
+// the Markdown code fence models like to wrap it in. If the config fails
+// validation, it is returned together with the validation error so the caller
+// can still inspect or save it; if it is not YAML at all, the error wraps
+// ErrInvalidYAML and the raw response text is returned instead.
+func GenerateGCSFuseConfig(ctx context.Context, client *genai.Client, workloadYAML []byte, samples []byte, opts GenerateOptions) (string, error) {
+	if opts.DryRun {
+		return dryRunPrompt(workloadYAML, samples, opts)
+	}
+	return generateConfig(ctx, newModel(client, opts), workloadYAML, samples, opts)
+}
+
+// newModel returns the model selected by opts, with its generation
+// parameters applied.
+func newModel(client *genai.Client, opts GenerateOptions) *genai.GenerativeModel {
+	model := client.GenerativeModel(modelName(opts))
+	model.Temperature = opts.Temperature
+	model.MaxOutputTokens = opts.MaxOutputTokens
+	return model
+}
+
+// modelName returns the model requested by opts, falling back to DefaultModel
+// for an empty name rather than letting the SDK fail on it.
+func modelName(opts GenerateOptions) string {
+	if name := strings.TrimSpace(opts.Model); name != "" {
+		return name
+	}
+	return DefaultModel
+}
+
+// generateConfig implements GenerateGCSFuseConfig for any contentGenerator.
+func generateConfig(ctx context.Context, model contentGenerator, workloadYAML []byte, samples []byte, opts GenerateOptions) (string, error) {
+	prompt, err := buildPrompt(workloadYAML, samples, opts)
+	if err != nil {
+		return "", err
+	}
+	if err := checkPromptSize(ctx, model, prompt, opts); err != nil {
+		return "", err
+	}
+	resp, err := generateWithDeadline(ctx, model, opts.timeout(), prompt...)
+	if err != nil {
+		return "", fmt.Errorf("generating config: %w", err)
+	}
+	return finishConfig(responseText(resp), opts)
+}
+
+// dryRunPrompt returns the prompt that would be sent, as described for
+// GenerateOptions.DryRun.
+func dryRunPrompt(workloadYAML []byte, samples []byte, opts GenerateOptions) (string, error) {
+	prompt, err := buildPrompt(workloadYAML, samples, opts)
+	if err != nil {
+		return "", err
+	}
+	return FormatPrompt(prompt), nil
+}
+
+// buildPrompt renders the prompt for a workload and adds the tuning guide and
+// the sample files.
+func buildPrompt(workloadYAML []byte, samples []byte, opts GenerateOptions) ([]genai.Part, error) {
+	promptTemplate := opts.PromptTemplate
+	if promptTemplate == "" {
+		promptTemplate = defaultPromptTemplate
+	}
+	promptText, err := renderPrompt(promptTemplate, promptData{
+		WorkloadDetails: string(workloadYAML),
+		SampleConfigs:   string(samples),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	prompt := []genai.Part{genai.Text(promptText)}
+	if opts.TuningGuide != nil {
+		prompt = append(prompt, *opts.TuningGuide)
+	}
+	for _, file := range opts.SampleFiles {
+		prompt = append(prompt, file)
+	}
+	return prompt, nil
+}
+
+// timeout returns the overall deadline requested by opts.
+func (opts GenerateOptions) timeout() time.Duration {
+	if opts.Timeout <= 0 {
+		return DefaultTimeout
+	}
+	return opts.Timeout
+}
+
+// finishConfig strips the code fence from the raw response text and validates
+// the config, as described for GenerateGCSFuseConfig.
+func finishConfig(raw string, opts GenerateOptions) (string, error) {
+	config := stripCodeFence(raw)
+
+	// Check the generated config before handing it out.
+	warnings, err := validateConfig(config)
+	if errors.Is(err, ErrInvalidYAML) {
+		return raw, err
+	}
+	for _, warning := range warnings {
+		if opts.OnWarning != nil {
+			opts.OnWarning(warning)
+		} else {
+			log.Printf("Warning: generated config: %s", warning)
+		}
+	}
+	return config, err
+}
+
+// WriteConfig writes config to w, or to standard output if w is nil, ending
+// it with a newline so it can be saved or printed as it is.
+func WriteConfig(w io.Writer, config string) error {
+	if w == nil {
+		w = os.Stdout
+	}
+	if !strings.HasSuffix(config, "\n") {
+		config += "\n"
+	}
+	if _, err := io.WriteString(w, config); err != nil {
+		return fmt.Errorf("writing config: %w", err)
+	}
+	return nil
+}
+
+// responseText concatenates the text of all candidates in resp.
+func responseText(resp *genai.GenerateContentResponse) string {
+	var builder strings.Builder
+	for _, cand := range resp.Candidates {
+		if cand.Content != nil {
+			for _, part := range cand.Content.Parts {
+				builder.WriteString(fmt.Sprintf("%v", part))
+			}
+		}
+	}
+	return builder.String()
+}
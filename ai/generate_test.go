@@ -0,0 +1,375 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	genai "github.com/google/generative-ai-go/genai"
+	"gopkg.in/yaml.v3"
+)
+
+func TestConsolidateTextFilesReportsUnreadableFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.yaml"), []byte("first: 1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "c.yaml"), []byte("third: 3"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// A dangling symlink is listed by the walk but cannot be read.
+	broken := filepath.Join(dir, "b.yaml")
+	if err := os.Symlink(filepath.Join(dir, "missing"), broken); err != nil {
+		t.Fatal(err)
+	}
+
+	content, fileErrs, err := ConsolidateTextFiles(context.Background(), dir, ConsolidateOptions{})
+	if err != nil {
+		t.Fatalf("ConsolidateTextFiles failed: %v", err)
+	}
+
+	for _, want := range []string{"first: 1", "third: 3"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("content is missing %q:\n%s", want, content)
+		}
+	}
+	if strings.Contains(content, broken) {
+		t.Errorf("content should not include the unreadable file:\n%s", content)
+	}
+	if len(fileErrs) != 1 {
+		t.Fatalf("got %d file errors, want 1: %v", len(fileErrs), fileErrs)
+	}
+	if fileErrs[0].Path != broken || !errors.Is(fileErrs[0], fs.ErrNotExist) {
+		t.Errorf("file error = %v, want not-exist error for %s", fileErrs[0], broken)
+	}
+}
+
+func TestConsolidateTextFilesWalkError(t *testing.T) {
+	_, _, err := ConsolidateTextFiles(context.Background(), filepath.Join(t.TempDir(), "missing"), ConsolidateOptions{})
+	if err == nil {
+		t.Error("expected an error for a missing folder")
+	}
+}
+
+// cancelFS cancels a context once limit different files have been opened,
+// simulating a walk that is still running when the caller gives up.
+type cancelFS struct {
+	fs.FS
+	cancel context.CancelFunc
+	limit  int
+	opened map[string]bool
+}
+
+func (c *cancelFS) Open(name string) (fs.File, error) {
+	f, err := c.FS.Open(name)
+	if err == nil && name != "." && !c.opened[name] {
+		if c.opened[name] = true; len(c.opened) == c.limit {
+			c.cancel()
+		}
+	}
+	return f, err
+}
+
+func TestConsolidateTextFilesCancelled(t *testing.T) {
+	fsys := fstest.MapFS{}
+	for i := 0; i < 10; i++ {
+		fsys[fmt.Sprintf("config-%d.yaml", i)] = &fstest.MapFile{Data: []byte("implicit-dirs: true")}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	slow := &cancelFS{FS: fsys, cancel: cancel, limit: 3, opened: make(map[string]bool)}
+
+	content, _, err := consolidateFiles(ctx, slow, "samples", ConsolidateOptions{})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if content.Text != "" {
+		t.Errorf("content = %q, want none after cancellation", content.Text)
+	}
+	if len(slow.opened) != 3 {
+		t.Errorf("opened %d files, want the walk to stop after 3", len(slow.opened))
+	}
+
+	if _, _, err := ConsolidateTextFiles(ctx, t.TempDir(), ConsolidateOptions{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled for a done context", err)
+	}
+}
+
+// slowModel answers after delay, ignoring the context.
+type slowModel struct {
+	delay time.Duration
+}
+
+func (m slowModel) GenerateContent(ctx context.Context, parts ...genai.Part) (*genai.GenerateContentResponse, error) {
+	time.Sleep(m.delay)
+	return &genai.GenerateContentResponse{}, nil
+}
+
+func TestGenerateWithDeadline(t *testing.T) {
+	start := time.Now()
+	_, err := generateWithDeadline(context.Background(), slowModel{delay: time.Second}, 20*time.Millisecond, genai.Text("prompt"))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("call returned after %v, want about the deadline", elapsed)
+	}
+
+	resp, err := generateWithDeadline(context.Background(), slowModel{}, time.Second, genai.Text("prompt"))
+	if err != nil || resp == nil {
+		t.Errorf("fast model got (%v, %v), want a response", resp, err)
+	}
+}
+
+// textModel answers with text and records the prompt it was given.
+type textModel struct {
+	text   string
+	prompt []genai.Part
+}
+
+func (m *textModel) GenerateContent(ctx context.Context, parts ...genai.Part) (*genai.GenerateContentResponse, error) {
+	m.prompt = parts
+	return &genai.GenerateContentResponse{
+		Candidates: []*genai.Candidate{{Content: &genai.Content{Parts: []genai.Part{genai.Text(m.text)}}}},
+	}, nil
+}
+
+func TestGenerateConfig(t *testing.T) {
+	model := &textModel{text: "implicit-dirs: true\nmetadata-cache:\n  ttl-secs: -1\nbogus: 1\n"}
+	guide := &genai.FileData{MIMEType: "application/pdf", URI: "guide-uri"}
+	var warnings []string
+	config, err := generateConfig(context.Background(), model, []byte("serving on TPU"), []byte("SAMPLES"), GenerateOptions{
+		PromptTemplate: "{{.WorkloadDetails}}|{{.SampleConfigs}}",
+		TuningGuide:    guide,
+		OnWarning:      func(w string) { warnings = append(warnings, w) },
+	})
+	if err != nil {
+		t.Fatalf("generateConfig failed: %v", err)
+	}
+	if config != model.text {
+		t.Errorf("config = %q, want %q", config, model.text)
+	}
+	if len(model.prompt) != 2 || model.prompt[0] != genai.Text("serving on TPU|SAMPLES") || model.prompt[1] != *guide {
+		t.Errorf("prompt = %v, want the rendered template and the tuning guide", model.prompt)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "bogus") {
+		t.Errorf("warnings = %q, want one about the unknown key", warnings)
+	}
+}
+
+func TestGenerateConfigInvalid(t *testing.T) {
+	model := &textModel{text: "implicit-dirs: true\n"}
+	config, err := generateConfig(context.Background(), model, nil, nil, GenerateOptions{})
+	if err == nil {
+		t.Error("expected an error for a config missing required keys")
+	}
+	if config != model.text {
+		t.Errorf("config = %q, want the invalid config %q", config, model.text)
+	}
+	if len(model.prompt) != 1 {
+		t.Errorf("prompt has %d parts, want only the text without a tuning guide", len(model.prompt))
+	}
+}
+
+func TestNewModelOptions(t *testing.T) {
+	for _, name := range []string{"", "  "} {
+		if got := modelName(GenerateOptions{Model: name}); got != DefaultModel {
+			t.Errorf("modelName(%q) = %q, want %q", name, got, DefaultModel)
+		}
+	}
+	if got := modelName(GenerateOptions{Model: "gemini-2.5-flash"}); got != "gemini-2.5-flash" {
+		t.Errorf("modelName = %q, want gemini-2.5-flash", got)
+	}
+
+	model := newModel(&genai.Client{}, GenerateOptions{Temperature: genai.Ptr[float32](0.1), MaxOutputTokens: genai.Ptr[int32](2048)})
+	if model.Temperature == nil || *model.Temperature != 0.1 {
+		t.Errorf("Temperature = %v, want 0.1", model.Temperature)
+	}
+	if model.MaxOutputTokens == nil || *model.MaxOutputTokens != 2048 {
+		t.Errorf("MaxOutputTokens = %v, want 2048", model.MaxOutputTokens)
+	}
+	if model := newModel(&genai.Client{}, GenerateOptions{}); model.Temperature != nil || model.MaxOutputTokens != nil {
+		t.Errorf("generation parameters = %v, %v, want the model defaults", model.Temperature, model.MaxOutputTokens)
+	}
+}
+
+func TestGenerateConfigStripsCodeFence(t *testing.T) {
+	model := &textModel{text: "```yaml\nimplicit-dirs: true\nmetadata-cache:\n  ttl-secs: 60\n```\n"}
+	config, err := generateConfig(context.Background(), model, nil, nil, GenerateOptions{})
+	if err != nil {
+		t.Fatalf("generateConfig failed: %v", err)
+	}
+	if want := "implicit-dirs: true\nmetadata-cache:\n  ttl-secs: 60\n"; config != want {
+		t.Errorf("config = %q, want %q", config, want)
+	}
+	var parsed map[string]any
+	if err := yaml.Unmarshal([]byte(config), &parsed); err != nil || parsed["implicit-dirs"] != true {
+		t.Errorf("config does not parse: %v, %v", parsed, err)
+	}
+
+	model = &textModel{text: "```yaml\nimplicit-dirs: [true\n```"}
+	config, err = generateConfig(context.Background(), model, nil, nil, GenerateOptions{})
+	if !errors.Is(err, ErrInvalidYAML) {
+		t.Errorf("error = %v, want %v", err, ErrInvalidYAML)
+	}
+	if config != model.text {
+		t.Errorf("config = %q, want the raw response %q", config, model.text)
+	}
+}
+
+func TestConsolidateSamplesMixedFolder(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"gpu.yaml":       []byte("implicit-dirs: true"),
+		"notes.txt":      []byte("Serving reads sequentially."),
+		"guide.pdf":      []byte("%PDF-1.7\n\x00\x01binary"),
+		"diagram.png":    []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"),
+		"nested/blob":    {0x00, 0x01, 0x02, 0xff},
+		"nested/tpu.yml": []byte("file-cache: {}"),
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	samples, fileErrs, err := ConsolidateSamples(context.Background(), dir, ConsolidateOptions{})
+	if err != nil || len(fileErrs) != 0 {
+		t.Fatalf("ConsolidateSamples failed: %v, %v", err, fileErrs)
+	}
+	for _, want := range []string{"implicit-dirs: true", "Serving reads sequentially.", "file-cache: {}"} {
+		if !strings.Contains(samples.Text, want) {
+			t.Errorf("text is missing %q:\n%s", want, samples.Text)
+		}
+	}
+	for _, unwanted := range []string{"guide.pdf", "diagram.png", "blob", "PDF", "PNG"} {
+		if strings.Contains(samples.Text, unwanted) {
+			t.Errorf("text should not include %q:\n%s", unwanted, samples.Text)
+		}
+	}
+
+	want := []SampleFile{
+		{Path: filepath.Join(dir, "diagram.png"), MIMEType: "image/png"},
+		{Path: filepath.Join(dir, "guide.pdf"), MIMEType: "application/pdf"},
+		{Path: filepath.Join(dir, "nested", "blob"), MIMEType: "application/octet-stream"},
+	}
+	if len(samples.Files) != len(want) {
+		t.Fatalf("files = %v, want %v", samples.Files, want)
+	}
+	for i := range want {
+		if samples.Files[i] != want[i] {
+			t.Errorf("files[%d] = %v, want %v", i, samples.Files[i], want[i])
+		}
+	}
+
+	text, _, err := ConsolidateTextFiles(context.Background(), dir, ConsolidateOptions{})
+	if err != nil || text != samples.Text {
+		t.Errorf("ConsolidateTextFiles = %q, %v, want the text of ConsolidateSamples", text, err)
+	}
+}
+
+func TestBuildPromptAddsSampleFiles(t *testing.T) {
+	guide := genai.FileData{MIMEType: "application/pdf", URI: "files/guide"}
+	diagram := genai.FileData{MIMEType: "image/png", URI: "files/diagram"}
+	prompt, err := buildPrompt([]byte("workload"), []byte("samples"), GenerateOptions{
+		TuningGuide: &guide,
+		SampleFiles: []genai.FileData{diagram},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prompt) != 3 || prompt[1] != guide || prompt[2] != diagram {
+		t.Errorf("prompt = %v, want the text, the tuning guide and the sample file", prompt)
+	}
+}
+
+func TestGenerateConfigDryRun(t *testing.T) {
+	// Without a client, any model call would panic.
+	guide := &genai.FileData{MIMEType: "application/pdf", URI: "files/guide"}
+	prompt, err := GenerateGCSFuseConfig(context.Background(), nil, []byte("serving on TPU"), []byte("implicit-dirs: false"), GenerateOptions{
+		TuningGuide: guide,
+		DryRun:      true,
+	})
+	if err != nil {
+		t.Fatalf("GenerateGCSFuseConfig failed: %v", err)
+	}
+	for _, want := range []string{
+		"serving on TPU",
+		"--- START OF SAMPLE CONFIGURATIONS ---\nimplicit-dirs: false\n--- END OF SAMPLE CONFIGURATIONS ---",
+		"--- FILE files/guide (application/pdf) ---",
+	} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt = %q, want it to contain %q", prompt, want)
+		}
+	}
+}
+
+func TestWriteConfig(t *testing.T) {
+	for _, config := range []string{"implicit-dirs: true", "implicit-dirs: true\n"} {
+		var buf bytes.Buffer
+		if err := WriteConfig(&buf, config); err != nil {
+			t.Fatalf("WriteConfig(%q) failed: %v", config, err)
+		}
+		if got, want := buf.String(), "implicit-dirs: true\n"; got != want {
+			t.Errorf("WriteConfig(%q) wrote %q, want %q", config, got, want)
+		}
+	}
+}
+
+func TestConsolidateTextFilesFilters(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"small.yaml":          "implicit-dirs: true",
+		"big.yaml":            strings.Repeat("x", 100),
+		"notes.txt":           "not a config",
+		"tool.bin":            "\x00\x01\x02",
+		"nested/gpu.yaml":     "file-cache: {}",
+		"logs/huge.yaml":      "excluded folder",
+		"nested/skip.yaml":    "excluded by path",
+		"nested/deeper/x.yml": "other extension",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	content, fileErrs, err := ConsolidateTextFiles(context.Background(), dir, ConsolidateOptions{
+		Include:     []string{"*.yaml"},
+		Exclude:     []string{"logs", "nested/skip.yaml"},
+		MaxFileSize: 20,
+	})
+	if err != nil || len(fileErrs) != 0 {
+		t.Fatalf("ConsolidateTextFiles failed: %v, %v", err, fileErrs)
+	}
+	for _, want := range []string{"small.yaml ---\nimplicit-dirs: true\n--- END", "gpu.yaml ---\nfile-cache: {}\n--- END", "big.yaml ---\n" + strings.Repeat("x", 20) + "\n--- TRUNCATED:"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("content is missing %q:\n%s", want, content)
+		}
+	}
+	for _, unwanted := range []string{"notes.txt", "tool.bin", "huge.yaml", "skip.yaml", "x.yml", strings.Repeat("x", 21)} {
+		if strings.Contains(content, unwanted) {
+			t.Errorf("content should not include %q:\n%s", unwanted, content)
+		}
+	}
+
+	if _, _, err := ConsolidateTextFiles(context.Background(), dir, ConsolidateOptions{Include: []string{"["}}); err == nil {
+		t.Error("expected an error for a malformed pattern")
+	}
+}
@@ -0,0 +1,84 @@
+package ai
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	genai "github.com/google/generative-ai-go/genai"
+)
+
+// defaultPromptTemplate is the prompt used unless GenerateOptions supplies a
+// custom one. Templates can refer to the fields of promptData.
+const defaultPromptTemplate = `Use the tuning guide to understand what values to configure.
+I have also added some sample gcsfuse configs for gpu and tpu for checkpointing, serving and training workload.
+Give equal importance to all sources and combine the details from all these sources.
+Checkpointing is primarily write workload. Serving is mostly sequential read workload. Training is mostly random read workload.
+Use cache-dir as /tmp if cache-dir is needed. File cache should be enabled only when the workload is not too big and can fit in the disk
+
+Generate a config for GCSFuse for the provided workload.
+Just generate a YAML file which can be saved directly to a file.
+
+Start of workload data
+{{.WorkloadDetails}}
+End of workload data
+
+--- START OF SAMPLE CONFIGURATIONS ---
+{{.SampleConfigs}}
+--- END OF SAMPLE CONFIGURATIONS ---
+`
+
+// promptData holds the values substituted into the prompt template.
+type promptData struct {
+	WorkloadDetails string // Contents of the workload details file.
+	SampleConfigs   string // Sample configs as returned by ConsolidateTextFiles.
+}
+
+// LoadPromptTemplate returns the template text stored at path, or the default
+// template if path is empty. Templates can refer to the WorkloadDetails and
+// SampleConfigs fields.
+func LoadPromptTemplate(path string) (string, error) {
+	if path == "" {
+		return defaultPromptTemplate, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("reading prompt template: %w", err)
+	}
+	return string(content), nil
+}
+
+// renderPrompt executes the prompt template text with data. Referring to a
+// field that promptData does not have is an error.
+func renderPrompt(text string, data promptData) (string, error) {
+	tmpl, err := template.New("prompt").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("parsing prompt template: %w", err)
+	}
+	var builder strings.Builder
+	if err := tmpl.Execute(&builder, data); err != nil {
+		return "", fmt.Errorf("rendering prompt template: %w", err)
+	}
+	return builder.String(), nil
+}
+
+// FormatPrompt renders prompt, as assembled for the model, for people to read.
+// Text parts are written as they are; file parts, e.g. the tuning guide, are
+// written as a marker naming the uploaded file.
+func FormatPrompt(prompt []genai.Part) string {
+	var builder strings.Builder
+	for _, part := range prompt {
+		switch part := part.(type) {
+		case genai.Text:
+			builder.WriteString("--- START OF PROMPT TEXT ---\n")
+			builder.WriteString(strings.TrimSuffix(string(part), "\n"))
+			builder.WriteString("\n--- END OF PROMPT TEXT ---\n")
+		case genai.FileData:
+			fmt.Fprintf(&builder, "--- FILE %s (%s) ---\n", part.URI, part.MIMEType)
+		default:
+			fmt.Fprintf(&builder, "--- %T PART ---\n", part)
+		}
+	}
+	return builder.String()
+}
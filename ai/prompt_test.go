@@ -0,0 +1,50 @@
+package ai
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenderPromptCustomTemplate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prompt.tmpl")
+	custom := "Workload: {{.WorkloadDetails}}\nSamples: {{.SampleConfigs}}\n"
+	if err := os.WriteFile(path, []byte(custom), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	text, err := LoadPromptTemplate(path)
+	if err != nil {
+		t.Fatalf("LoadPromptTemplate failed: %v", err)
+	}
+	got, err := renderPrompt(text, promptData{WorkloadDetails: "serving on TPU", SampleConfigs: "file-cache: {}"})
+	if err != nil {
+		t.Fatalf("renderPrompt failed: %v", err)
+	}
+	if want := "Workload: serving on TPU\nSamples: file-cache: {}\n"; got != want {
+		t.Errorf("renderPrompt = %q, want %q", got, want)
+	}
+}
+
+func TestRenderPromptDefaultTemplate(t *testing.T) {
+	text, err := LoadPromptTemplate("")
+	if err != nil {
+		t.Fatalf("LoadPromptTemplate failed: %v", err)
+	}
+	got, err := renderPrompt(text, promptData{WorkloadDetails: "WORKLOAD", SampleConfigs: "SAMPLES"})
+	if err != nil {
+		t.Fatalf("renderPrompt failed: %v", err)
+	}
+	for _, want := range []string{"Start of workload data\nWORKLOAD\nEnd of workload data", "SAMPLES", "Generate a config for GCSFuse"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("default prompt is missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestRenderPromptUnknownField(t *testing.T) {
+	if _, err := renderPrompt("{{.Unknown}}", promptData{}); err == nil {
+		t.Error("expected an error for an unknown placeholder")
+	}
+}
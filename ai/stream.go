@@ -0,0 +1,87 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	genai "github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/iterator"
+)
+
+// responseIterator yields the chunks of a streamed response, like
+// *genai.GenerateContentResponseIterator, and returns iterator.Done after the
+// last one.
+type responseIterator interface {
+	Next() (*genai.GenerateContentResponse, error)
+}
+
+// contentStreamer starts a streamed generation, so tests can substitute a
+// fake model.
+type contentStreamer interface {
+	streamContent(ctx context.Context, parts ...genai.Part) responseIterator
+}
+
+// modelStreamer adapts *genai.GenerativeModel to contentStreamer.
+type modelStreamer struct {
+	model *genai.GenerativeModel
+}
+
+// CountTokens implements TokenCounter, so the prompt budget is checked with
+// the model.
+func (m modelStreamer) CountTokens(ctx context.Context, parts ...genai.Part) (*genai.CountTokensResponse, error) {
+	return m.model.CountTokens(ctx, parts...)
+}
+
+func (m modelStreamer) streamContent(ctx context.Context, parts ...genai.Part) responseIterator {
+	return m.model.GenerateContentStream(ctx, parts...)
+}
+
+// GenerateGCSFuseConfigStream is GenerateGCSFuseConfig, but streams the
+// response: the text of every chunk is written to w as soon as it arrives,
+// so callers can show progress during long generations. w receives the raw
+// response, code fence included; the returned config is stripped and
+// validated as for GenerateGCSFuseConfig once the stream ends. An error in
+// the middle of the stream is returned after whatever arrived before it was
+// written, with an empty config.
+func GenerateGCSFuseConfigStream(ctx context.Context, client *genai.Client, workloadYAML []byte, samples []byte, w io.Writer, opts GenerateOptions) (string, error) {
+	if opts.DryRun {
+		return dryRunPrompt(workloadYAML, samples, opts)
+	}
+	return generateConfigStream(ctx, modelStreamer{newModel(client, opts)}, workloadYAML, samples, w, opts)
+}
+
+// generateConfigStream implements GenerateGCSFuseConfigStream for any
+// contentStreamer.
+func generateConfigStream(ctx context.Context, model contentStreamer, workloadYAML []byte, samples []byte, w io.Writer, opts GenerateOptions) (string, error) {
+	prompt, err := buildPrompt(workloadYAML, samples, opts)
+	if err != nil {
+		return "", err
+	}
+	if err := checkPromptSize(ctx, model, prompt, opts); err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.timeout())
+	defer cancel()
+
+	var raw strings.Builder
+	iter := model.streamContent(ctx, prompt...)
+	for {
+		resp, err := iter.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("generating config: %w", err)
+		}
+		chunk := responseText(resp)
+		raw.WriteString(chunk)
+		if _, err := io.WriteString(w, chunk); err != nil {
+			return "", fmt.Errorf("writing config: %w", err)
+		}
+	}
+	return finishConfig(raw.String(), opts)
+}
@@ -0,0 +1,76 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	genai "github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/iterator"
+)
+
+// chunkStreamer streams chunks one response at a time and then ends with err,
+// or iterator.Done if err is nil.
+type chunkStreamer struct {
+	chunks []string
+	err    error
+}
+
+func (m *chunkStreamer) streamContent(ctx context.Context, parts ...genai.Part) responseIterator {
+	return m
+}
+
+func (m *chunkStreamer) Next() (*genai.GenerateContentResponse, error) {
+	if len(m.chunks) == 0 {
+		if m.err != nil {
+			return nil, m.err
+		}
+		return nil, iterator.Done
+	}
+	chunk := m.chunks[0]
+	m.chunks = m.chunks[1:]
+	return &genai.GenerateContentResponse{
+		Candidates: []*genai.Candidate{{Content: &genai.Content{Parts: []genai.Part{genai.Text(chunk)}}}},
+	}, nil
+}
+
+// recordingWriter keeps every Write separately.
+type recordingWriter struct {
+	writes []string
+}
+
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	w.writes = append(w.writes, string(p))
+	return len(p), nil
+}
+
+func TestGenerateConfigStream(t *testing.T) {
+	chunks := []string{"```yaml\n", "implicit-dirs: true\n", "metadata-cache:\n  ttl-secs: 60\n", "```\n"}
+	var out recordingWriter
+	config, err := generateConfigStream(context.Background(), &chunkStreamer{chunks: chunks}, nil, nil, &out, GenerateOptions{})
+	if err != nil {
+		t.Fatalf("generateConfigStream failed: %v", err)
+	}
+	if strings.Join(out.writes, "|") != strings.Join(chunks, "|") {
+		t.Errorf("writes = %q, want each chunk as it arrives %q", out.writes, chunks)
+	}
+	if want := "implicit-dirs: true\nmetadata-cache:\n  ttl-secs: 60\n"; config != want {
+		t.Errorf("config = %q, want %q", config, want)
+	}
+}
+
+func TestGenerateConfigStreamError(t *testing.T) {
+	errQuota := errors.New("quota exceeded")
+	var out recordingWriter
+	config, err := generateConfigStream(context.Background(), &chunkStreamer{chunks: []string{"implicit-dirs: true\n"}, err: errQuota}, nil, nil, &out, GenerateOptions{})
+	if !errors.Is(err, errQuota) {
+		t.Errorf("error = %v, want %v", err, errQuota)
+	}
+	if config != "" {
+		t.Errorf("config = %q, want none after a failed stream", config)
+	}
+	if len(out.writes) != 1 {
+		t.Errorf("got %d writes, want the chunk received before the error", len(out.writes))
+	}
+}
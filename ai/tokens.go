@@ -0,0 +1,61 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	genai "github.com/google/generative-ai-go/genai"
+)
+
+// ErrPromptTooLarge is returned when the prompt exceeds
+// GenerateOptions.MaxPromptTokens, before it is sent to the model.
+var ErrPromptTooLarge = errors.New("prompt exceeds the token budget")
+
+// bytesPerToken is the rough ratio of text to tokens used when no
+// TokenCounter is available.
+const bytesPerToken = 4
+
+// TokenCounter counts the tokens of a prompt. *genai.GenerativeModel
+// implements it with the CountTokens API.
+type TokenCounter interface {
+	CountTokens(ctx context.Context, parts ...genai.Part) (*genai.CountTokensResponse, error)
+}
+
+// EstimatePromptSize returns how many tokens parts take, as counted by
+// counter. If counter is nil, it estimates one token per four bytes of text
+// and leaves file parts out, as their size is not known locally.
+func EstimatePromptSize(ctx context.Context, counter TokenCounter, parts []genai.Part) (int, error) {
+	if counter == nil {
+		size := 0
+		for _, part := range parts {
+			if text, ok := part.(genai.Text); ok {
+				size += len(text)
+			}
+		}
+		return (size + bytesPerToken - 1) / bytesPerToken, nil
+	}
+	resp, err := counter.CountTokens(ctx, parts...)
+	if err != nil {
+		return 0, fmt.Errorf("counting prompt tokens: %w", err)
+	}
+	return int(resp.TotalTokens), nil
+}
+
+// checkPromptSize returns an error wrapping ErrPromptTooLarge if prompt
+// exceeds the budget set by opts. model counts the tokens if it is a
+// TokenCounter; otherwise they are estimated.
+func checkPromptSize(ctx context.Context, model any, prompt []genai.Part, opts GenerateOptions) error {
+	if opts.MaxPromptTokens <= 0 {
+		return nil
+	}
+	counter, _ := model.(TokenCounter)
+	tokens, err := EstimatePromptSize(ctx, counter, prompt)
+	if err != nil {
+		return err
+	}
+	if tokens > opts.MaxPromptTokens {
+		return fmt.Errorf("%w: %d tokens, budget is %d", ErrPromptTooLarge, tokens, opts.MaxPromptTokens)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	genai "github.com/google/generative-ai-go/genai"
+)
+
+// countingModel is a textModel that also counts tokens, reporting tokens for
+// every prompt.
+type countingModel struct {
+	textModel
+	tokens int32
+	err    error
+	counts int
+}
+
+func (m *countingModel) CountTokens(ctx context.Context, parts ...genai.Part) (*genai.CountTokensResponse, error) {
+	m.counts++
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &genai.CountTokensResponse{TotalTokens: m.tokens}, nil
+}
+
+func TestEstimatePromptSize(t *testing.T) {
+	parts := []genai.Part{genai.Text("12345678"), genai.Text("9"), genai.FileData{URI: "files/guide"}}
+	if got, err := EstimatePromptSize(context.Background(), nil, parts); err != nil || got != 3 {
+		t.Errorf("EstimatePromptSize without a counter = %d, %v, want 3 tokens for 9 bytes of text", got, err)
+	}
+
+	model := &countingModel{tokens: 1234}
+	if got, err := EstimatePromptSize(context.Background(), model, parts); err != nil || got != 1234 {
+		t.Errorf("EstimatePromptSize = %d, %v, want the count of the model", got, err)
+	}
+
+	model.err = errors.New("quota exceeded")
+	if _, err := EstimatePromptSize(context.Background(), model, parts); !errors.Is(err, model.err) {
+		t.Errorf("err = %v, want the error of the counter", err)
+	}
+}
+
+func TestGenerateConfigChecksPromptBudget(t *testing.T) {
+	model := &countingModel{textModel: textModel{text: "implicit-dirs: true\nmetadata-cache:\n  ttl-secs: -1\n"}, tokens: 5000}
+	opts := GenerateOptions{MaxPromptTokens: 4096}
+
+	_, err := generateConfig(context.Background(), model, []byte("workload"), []byte("samples"), opts)
+	if !errors.Is(err, ErrPromptTooLarge) || !strings.Contains(err.Error(), "5000 tokens") {
+		t.Fatalf("err = %v, want ErrPromptTooLarge naming the count", err)
+	}
+	if model.prompt != nil {
+		t.Error("the prompt should not be sent once it is over budget")
+	}
+
+	model.tokens = 4096
+	if _, err := generateConfig(context.Background(), model, []byte("workload"), []byte("samples"), opts); err != nil {
+		t.Fatalf("generateConfig failed within budget: %v", err)
+	}
+	if model.prompt == nil {
+		t.Error("the prompt should be sent within budget")
+	}
+
+	// Without a budget the tokens are not counted at all.
+	model.counts = 0
+	if _, err := generateConfig(context.Background(), model, []byte("workload"), []byte("samples"), GenerateOptions{}); err != nil || model.counts != 0 {
+		t.Errorf("generateConfig without a budget = %v after %d counts, want no counting", err, model.counts)
+	}
+
+	// A model that cannot count falls back to the size of the text.
+	_, err = generateConfig(context.Background(), &textModel{}, []byte(strings.Repeat("x", 400)), nil, GenerateOptions{MaxPromptTokens: 50})
+	if !errors.Is(err, ErrPromptTooLarge) {
+		t.Errorf("err = %v, want ErrPromptTooLarge from the estimate", err)
+	}
+}
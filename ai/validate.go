@@ -0,0 +1,169 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// valueKind is the YAML type expected for a config key.
+type valueKind int
+
+const (
+	kindSection valueKind = iota // Nested mapping of further keys.
+	kindBool
+	kindInt
+	kindString
+)
+
+func (k valueKind) String() string {
+	switch k {
+	case kindSection:
+		return "section"
+	case kindBool:
+		return "bool"
+	case kindInt:
+		return "int"
+	case kindString:
+		return "string"
+	default:
+		return "unknown"
+	}
+}
+
+// keySpec describes one known GCSFuse config key.
+type keySpec struct {
+	kind valueKind
+	min  int64 // Smallest allowed value for int keys.
+	max  int64 // Largest allowed value for int keys; zero means no limit.
+}
+
+// configSchema lists the GCSFuse config keys the generator is expected to
+// produce, by dotted path. -1 means "unlimited" for the cache sizes and TTLs.
+var configSchema = map[string]keySpec{
+	"implicit-dirs": {kind: kindBool},
+	"cache-dir":     {kind: kindString},
+
+	"metadata-cache":                        {kind: kindSection},
+	"metadata-cache.ttl-secs":               {kind: kindInt, min: -1},
+	"metadata-cache.negative-ttl-secs":      {kind: kindInt, min: -1},
+	"metadata-cache.stat-cache-max-size-mb": {kind: kindInt, min: -1},
+	"metadata-cache.type-cache-max-size-mb": {kind: kindInt, min: -1},
+
+	"file-cache":                             {kind: kindSection},
+	"file-cache.max-size-mb":                 {kind: kindInt, min: -1},
+	"file-cache.cache-file-for-range-read":   {kind: kindBool},
+	"file-cache.enable-parallel-downloads":   {kind: kindBool},
+	"file-cache.parallel-downloads-per-file": {kind: kindInt, min: 1},
+	"file-cache.download-chunk-size-mb":      {kind: kindInt, min: 1},
+
+	"gcs-connection":                         {kind: kindSection},
+	"gcs-connection.sequential-read-size-mb": {kind: kindInt, min: 1, max: 1024},
+
+	"write":                         {kind: kindSection},
+	"write.enable-streaming-writes": {kind: kindBool},
+}
+
+// ErrInvalidYAML is returned, wrapped, for a generated config that cannot be
+// parsed at all.
+var ErrInvalidYAML = errors.New("generated config is not valid YAML")
+
+// requiredKeys must be present in every generated config. All sample configs
+// set them.
+var requiredKeys = []string{"implicit-dirs", "metadata-cache"}
+
+// validateConfig checks a generated config against configSchema. Unknown keys,
+// values of the wrong type and out-of-range values are returned as warnings.
+// An error is returned only if the config cannot be parsed or a required key
+// is missing. A surrounding Markdown code fence, as models often add, is
+// ignored.
+func validateConfig(content string) ([]string, error) {
+	var config map[string]any
+	if err := yaml.Unmarshal([]byte(stripCodeFence(content)), &config); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidYAML, err)
+	}
+
+	var warnings []string
+	validateSection("", config, &warnings)
+
+	var missing []string
+	for _, key := range requiredKeys {
+		if _, ok := config[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		return warnings, fmt.Errorf("generated config is missing required keys: %s", strings.Join(missing, ", "))
+	}
+	return warnings, nil
+}
+
+// validateSection appends warnings for the keys of section, whose dotted path
+// is prefix, recursing into nested sections.
+func validateSection(prefix string, section map[string]any, warnings *[]string) {
+	// Visit keys in order so warnings are stable.
+	keys := make([]string, 0, len(section))
+	for key := range section {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		value := section[key]
+		spec, ok := configSchema[path]
+		if !ok {
+			*warnings = append(*warnings, fmt.Sprintf("unknown key %q", path))
+			continue
+		}
+
+		switch spec.kind {
+		case kindSection:
+			nested, ok := value.(map[string]any)
+			if !ok {
+				*warnings = append(*warnings, fmt.Sprintf("key %q should be a section, got %T", path, value))
+				continue
+			}
+			validateSection(path, nested, warnings)
+		case kindBool:
+			if _, ok := value.(bool); !ok {
+				*warnings = append(*warnings, fmt.Sprintf("key %q should be a bool, got %T", path, value))
+			}
+		case kindString:
+			if _, ok := value.(string); !ok {
+				*warnings = append(*warnings, fmt.Sprintf("key %q should be a string, got %T", path, value))
+			}
+		case kindInt:
+			n, ok := value.(int)
+			if !ok {
+				*warnings = append(*warnings, fmt.Sprintf("key %q should be an int, got %T", path, value))
+				continue
+			}
+			if int64(n) < spec.min || (spec.max != 0 && int64(n) > spec.max) {
+				*warnings = append(*warnings, fmt.Sprintf("key %q has out-of-range value %d", path, n))
+			}
+		}
+	}
+}
+
+// stripCodeFence removes a Markdown code fence wrapping content, if any. The
+// unwrapped content ends with a single newline, ready to be saved.
+func stripCodeFence(content string) string {
+	trimmed := strings.TrimSpace(content)
+	if !strings.HasPrefix(trimmed, "```") {
+		return content
+	}
+	// Drop the opening fence line, which may name the language.
+	if i := strings.IndexByte(trimmed, '\n'); i >= 0 {
+		trimmed = trimmed[i+1:]
+	} else {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(trimmed), "```")) + "\n"
+}
@@ -0,0 +1,60 @@
+package ai
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateConfigReportsBadKeys(t *testing.T) {
+	config := "```yaml\n" + `implicit-dirs: true
+metadata-cache:
+  ttl-secs: -1
+gcs-connection:
+  sequential-read-size-mb: -5
+file-cache:
+  max-size-mb: lots
+  turbo-mode: true
+` + "```\n"
+
+	warnings, err := validateConfig(config)
+	if err != nil {
+		t.Fatalf("validateConfig failed: %v", err)
+	}
+	want := []string{
+		`key "file-cache.max-size-mb" should be an int, got string`,
+		`unknown key "file-cache.turbo-mode"`,
+		`key "gcs-connection.sequential-read-size-mb" has out-of-range value -5`,
+	}
+	if strings.Join(warnings, "\n") != strings.Join(want, "\n") {
+		t.Errorf("warnings = %q, want %q", warnings, want)
+	}
+}
+
+func TestValidateConfigMissingRequiredKey(t *testing.T) {
+	warnings, err := validateConfig("cache-dir: /tmp\n")
+	if err == nil || !strings.Contains(err.Error(), "implicit-dirs") || !strings.Contains(err.Error(), "metadata-cache") {
+		t.Errorf("error = %v, want missing implicit-dirs and metadata-cache", err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("unexpected warnings %q", warnings)
+	}
+}
+
+func TestValidateConfigAcceptsSamples(t *testing.T) {
+	paths, err := filepath.Glob("samples/gcsfuse_config/*/config_file/*.yaml")
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no sample configs found: %v", err)
+	}
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		warnings, err := validateConfig(string(content))
+		if err != nil || len(warnings) != 0 {
+			t.Errorf("%s: got warnings %q and error %v, want none", path, warnings, err)
+		}
+	}
+}
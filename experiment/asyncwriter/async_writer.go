@@ -0,0 +1,581 @@
+// Package asyncwriter provides AsyncWriter, an io.Writer that hands writes to
+// a background goroutine so callers such as loggers do not wait for I/O.
+package asyncwriter
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrCloseTimeout is returned by CloseWithTimeout when the writer goroutine
+// does not finish in time.
+var ErrCloseTimeout = errors.New("asyncwriter: close timed out")
+
+// ErrWriteTimeout is returned by Write when the buffer stays full for longer
+// than the timeout set with WithWriteTimeout.
+var ErrWriteTimeout = errors.New("asyncwriter: write timed out")
+
+// AsyncWriter provides an asynchronous, buffered writer.
+// It wraps an io.Writer and performs write operations in a separate goroutine.
+type AsyncWriter struct {
+	writer    io.Writer // Only accessed by the worker goroutine once started.
+	ch        chan asyncMessage
+	sendMu    sync.RWMutex // Held for reading while sending on ch, for writing to close it.
+	closeOnce sync.Once
+	closed    chan struct{}
+	exited    chan struct{} // Closed when the worker goroutine returns.
+	abandoned atomic.Bool   // Set by CloseWithTimeout; the worker discards what is left.
+	pending   atomic.Int64  // Queued writes the worker has not handed on yet.
+
+	// Progress since the last Flush, only accessed by the worker goroutine.
+	flushed  int   // Bytes written to the underlying writer.
+	flushErr error // First write error.
+
+	sequence bool       // Prefix each Write with its sequence number.
+	seqMu    sync.Mutex // Held while numbering and queueing a Write.
+	seq      uint64     // Last sequence number handed out, guarded by seqMu.
+
+	onFlush func(bytes int) // Called by the worker after each successful write.
+	onError func(err error) // Called by the worker after each failed write.
+	lastErr atomic.Pointer[error]
+
+	syncEvery int           // Every syncEvery-th Write waits for the worker; 0 disables.
+	writes    atomic.Uint64 // Number of Write calls so far.
+
+	dropOnFull bool          // Drop payloads instead of blocking when ch is full.
+	dropped    atomic.Uint64 // Payloads dropped because ch was full.
+
+	writeTimeout time.Duration // How long Write waits for room in ch; 0 waits forever.
+	maxBuffered  atomic.Int64  // Highest len(ch) seen right after a send.
+
+	// Coalescing set up by NewAsyncWriterWithFlush, only used by the worker.
+	buf           *bufio.Writer // Nil when every payload is written on its own.
+	flushInterval time.Duration // Period of the worker's flush ticker; 0 disables.
+}
+
+// AsyncWriterOption configures optional behaviour of an AsyncWriter.
+type AsyncWriterOption func(*AsyncWriter)
+
+// WithSequenceNumbers prefixes the payload of every Write with a decimal
+// sequence number and a space, i.e. "<seq> <payload>". Numbers start at 1 and
+// increase by one per Write call, in the order the payloads are queued and
+// thus written, so a consumer seeing a gap knows the missing writes never
+// reached the underlying writer. Concurrent Writes take turns to be numbered
+// and queued.
+func WithSequenceNumbers() AsyncWriterOption {
+	return func(aw *AsyncWriter) {
+		aw.sequence = true
+	}
+}
+
+// asyncMessage is an item on the AsyncWriter channel: either a payload to write
+// or a control function that the worker runs in order with the payloads.
+type asyncMessage struct {
+	data    *[]byte // Pooled copy of the payload, see getBuffer.
+	control func()
+}
+
+// OnFlush registers fn to be called by the worker goroutine after every
+// successful write to the underlying writer, with the number of bytes written.
+// Callers can use it to advance a durable offset. fn must not call back into
+// the AsyncWriter.
+func OnFlush(fn func(bytes int)) AsyncWriterOption {
+	return func(aw *AsyncWriter) {
+		aw.onFlush = fn
+	}
+}
+
+// OnError registers fn to be called by the worker goroutine with the error of
+// every failed write to the underlying writer, instead of printing it to
+// stderr. Callers can use it to raise an alert or fail over to another sink,
+// e.g. with SwapWriter from another goroutine. fn runs without any lock held
+// but blocks the worker, and must not call back into the AsyncWriter itself.
+func OnError(fn func(err error)) AsyncWriterOption {
+	return func(aw *AsyncWriter) {
+		aw.onError = fn
+	}
+}
+
+// WithSyncEvery makes every nth Write block until it, and everything written
+// before it, has been handed to the underlying writer. The other writes stay
+// asynchronous, so n bounds how far the sink can lag behind the caller. n <= 0
+// leaves every write asynchronous.
+func WithSyncEvery(n int) AsyncWriterOption {
+	return func(aw *AsyncWriter) {
+		aw.syncEvery = n
+	}
+}
+
+// WithDropOnFull makes Write drop the payload instead of blocking when the
+// buffer is full. Such a Write still reports success; the loss shows up in
+// Dropped, and as a gap when combined with WithSequenceNumbers. By default
+// Write blocks until there is room.
+func WithDropOnFull() AsyncWriterOption {
+	return func(aw *AsyncWriter) {
+		aw.dropOnFull = true
+	}
+}
+
+// WithWriteTimeout makes Write fail with ErrWriteTimeout if the buffer is
+// still full after d, instead of blocking until there is room. The payload of
+// such a Write is not written. d <= 0 leaves Write blocking. It has no effect
+// with WithDropOnFull, which never blocks.
+func WithWriteTimeout(d time.Duration) AsyncWriterOption {
+	return func(aw *AsyncWriter) {
+		aw.writeTimeout = max(d, 0)
+	}
+}
+
+// NewAsyncWriter creates and starts a new AsyncWriter.
+// It takes an underlying io.Writer to write to and a bufferSize for the
+// internal channel, followed by optional settings.
+func NewAsyncWriter(w io.Writer, bufferSize int, opts ...AsyncWriterOption) *AsyncWriter {
+	if bufferSize <= 0 {
+		bufferSize = 1024 // Default buffer size
+	}
+	aw := &AsyncWriter{
+		writer: w,
+		ch:     make(chan asyncMessage, bufferSize),
+		closed: make(chan struct{}),
+		exited: make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(aw)
+	}
+	go aw.run()
+	return aw
+}
+
+// NewAsyncWriterWithFlush creates and starts an AsyncWriter that coalesces
+// payloads in a buffer of flushBytes bytes instead of writing each one on its
+// own. The buffer is written to w once it fills up, every flushInterval, before
+// Flush, SwapWriter and synchronous writes complete, and on Close. A
+// flushInterval <= 0 disables the periodic flush, leaving data in the buffer
+// until one of the other events; flushBytes <= 0 uses a 4 KiB buffer.
+func NewAsyncWriterWithFlush(w io.Writer, bufferSize, flushBytes int, flushInterval time.Duration, opts ...AsyncWriterOption) *AsyncWriter {
+	if flushBytes <= 0 {
+		flushBytes = 4096
+	}
+	coalesce := func(aw *AsyncWriter) {
+		aw.buf = bufio.NewWriterSize(sinkWriter{aw}, flushBytes)
+		aw.flushInterval = max(flushInterval, 0)
+	}
+	return NewAsyncWriter(w, bufferSize, append(opts, coalesce)...)
+}
+
+// run is the background worker goroutine that reads from the channel and
+// writes to the underlying writer.
+func (aw *AsyncWriter) run() {
+	defer close(aw.exited)
+	var tick <-chan time.Time
+	if aw.buf != nil && aw.flushInterval > 0 {
+		ticker := time.NewTicker(aw.flushInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case msg, ok := <-aw.ch:
+			if !ok {
+				aw.flushBuffer()
+				return
+			}
+			if msg.control != nil {
+				// Controls see everything queued before them as written.
+				aw.flushBuffer()
+				msg.control()
+				continue
+			}
+			aw.write(*msg.data)
+			putBuffer(msg.data)
+			aw.pending.Add(-1)
+		case <-tick:
+			aw.flushBuffer()
+		}
+	}
+}
+
+// write hands data to the coalescing buffer, or straight to the underlying
+// writer if there is none. It discards data once the writer is abandoned.
+func (aw *AsyncWriter) write(data []byte) {
+	if aw.abandoned.Load() {
+		return
+	}
+	if aw.buf == nil {
+		aw.writeOut(data)
+		return
+	}
+	if _, err := aw.buf.Write(data); err != nil {
+		aw.resetBuffer()
+	}
+}
+
+// flushBuffer writes out whatever the coalescing buffer holds, or discards it
+// once the writer is abandoned.
+func (aw *AsyncWriter) flushBuffer() {
+	if aw.buf == nil || aw.buf.Buffered() == 0 {
+		return
+	}
+	if aw.abandoned.Load() {
+		aw.resetBuffer()
+		return
+	}
+	if err := aw.buf.Flush(); err != nil {
+		aw.resetBuffer()
+	}
+}
+
+// resetBuffer clears the error a failed write leaves in the coalescing buffer,
+// dropping the data it still held. The error itself was already recorded by
+// writeOut.
+func (aw *AsyncWriter) resetBuffer() {
+	aw.buf.Reset(sinkWriter{aw})
+}
+
+// writeOut writes data to the underlying writer, keeping the Flush accounting
+// and reporting to OnFlush.
+func (aw *AsyncWriter) writeOut(data []byte) (int, error) {
+	n, err := writeAll(aw.writer, data)
+	aw.flushed += n
+	if err != nil {
+		if aw.flushErr == nil {
+			aw.flushErr = err
+		}
+		lastErr := err // Only failed writes pay for the heap copy.
+		aw.lastErr.Store(&lastErr)
+		if aw.onError != nil {
+			aw.onError(err)
+		} else {
+			fmt.Fprintf(os.Stderr, "AsyncWriter: write error: %v\n", err)
+		}
+		return n, err
+	}
+	if aw.onFlush != nil {
+		aw.onFlush(n)
+	}
+	return n, nil
+}
+
+// sinkWriter is the io.Writer the coalescing buffer writes through, so its
+// writes go to the current underlying writer and are accounted for.
+type sinkWriter struct {
+	aw *AsyncWriter
+}
+
+func (s sinkWriter) Write(p []byte) (int, error) {
+	return s.aw.writeOut(p)
+}
+
+// writeAll writes the whole of data to w, issuing further writes after a short
+// write, and returns the number of bytes written. A write that makes no
+// progress without reporting an error is treated as io.ErrShortWrite so a
+// stuck sink cannot spin the worker forever.
+func writeAll(w io.Writer, data []byte) (int, error) {
+	written := 0
+	for written < len(data) {
+		n, err := w.Write(data[written:])
+		if n > 0 {
+			written += n
+		}
+		if err != nil {
+			return written, err
+		}
+		if n <= 0 {
+			return written, io.ErrShortWrite
+		}
+	}
+	return written, nil
+}
+
+// Write sends data to the writer's buffer. It is non-blocking unless the
+// buffer is full, in which case it waits, up to the WithWriteTimeout if one is
+// set, or, with WithDropOnFull, drops data and returns immediately. It makes a
+// copy of the provided byte slice, so the caller is free to reuse the original
+// slice.
+func (aw *AsyncWriter) Write(p []byte) (int, error) {
+	if err := aw.enqueue(p); err != nil {
+		return 0, err
+	}
+	if aw.syncEvery > 0 && aw.writes.Add(1)%uint64(aw.syncEvery) == 0 {
+		if err := aw.wait(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// enqueue queues a copy of p for the worker, prefixed with its sequence number
+// with WithSequenceNumbers, or drops it with WithDropOnFull if the buffer is
+// full.
+func (aw *AsyncWriter) enqueue(p []byte) error {
+	if aw.sequence {
+		// Number and queue the payload in one critical section, so numbers
+		// reach the worker in increasing order.
+		aw.seqMu.Lock()
+		defer aw.seqMu.Unlock()
+	}
+
+	// Make a copy of the data, as the caller might reuse the buffer p.
+	buf := getBuffer()
+	data := (*buf)[:0]
+	if aw.sequence {
+		aw.seq++
+		data = strconv.AppendUint(data, aw.seq, 10)
+		data = append(data, ' ')
+	}
+	*buf = append(data, p...)
+
+	msg := asyncMessage{data: buf}
+	aw.pending.Add(1)
+	if aw.dropOnFull {
+		queued, err := aw.offer(msg)
+		if !queued {
+			aw.pending.Add(-1)
+			putBuffer(buf)
+		}
+		if err != nil {
+			return err
+		}
+		if !queued {
+			aw.dropped.Add(1)
+		}
+		return nil
+	}
+	if err := aw.sendWithin(msg, aw.writeTimeout); err != nil {
+		aw.pending.Add(-1)
+		putBuffer(buf)
+		return err
+	}
+	return nil
+}
+
+// Payload copies are recycled through bufferPool. A new buffer is sized by the
+// first payload copied into it and keeps its capacity when reused, so buffers
+// settle at the size of typical log lines. Buffers that grew past
+// maxPooledBuffer are left to the garbage collector so a single large write
+// does not pin memory.
+const maxPooledBuffer = 64 << 10
+
+var bufferPool = sync.Pool{
+	New: func() any { return new([]byte) },
+}
+
+// getBuffer returns an empty buffer from bufferPool.
+func getBuffer() *[]byte {
+	buf := bufferPool.Get().(*[]byte)
+	*buf = (*buf)[:0]
+	return buf
+}
+
+// putBuffer returns buf to bufferPool once the worker is done with it.
+func putBuffer(buf *[]byte) {
+	if cap(*buf) > maxPooledBuffer {
+		return
+	}
+	bufferPool.Put(buf)
+}
+
+// wait blocks until the worker has processed everything queued before the call.
+func (aw *AsyncWriter) wait() error {
+	done := make(chan struct{})
+	if err := aw.send(asyncMessage{control: func() { close(done) }}); err != nil {
+		return err
+	}
+	<-done
+	return nil
+}
+
+// send queues msg for the worker, blocking while the channel is full. It fails
+// with io.ErrClosedPipe once the writer is closed.
+func (aw *AsyncWriter) send(msg asyncMessage) error {
+	return aw.sendWithin(msg, 0)
+}
+
+// sendWithin is send, but fails with ErrWriteTimeout if the channel is still
+// full after timeout. A timeout of 0 waits forever.
+func (aw *AsyncWriter) sendWithin(msg asyncMessage, timeout time.Duration) error {
+	aw.sendMu.RLock()
+	defer aw.sendMu.RUnlock()
+	select {
+	case <-aw.closed:
+		return io.ErrClosedPipe
+	default:
+	}
+
+	select {
+	case aw.ch <- msg:
+		aw.observeBuffered()
+		return nil
+	default:
+	}
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	select {
+	case aw.ch <- msg:
+		aw.observeBuffered()
+		return nil
+	case <-aw.closed:
+		return io.ErrClosedPipe
+	case <-expired:
+		return fmt.Errorf("%w after %v", ErrWriteTimeout, timeout)
+	}
+}
+
+// observeBuffered records the current channel occupancy for MaxBuffered.
+func (aw *AsyncWriter) observeBuffered() {
+	n := int64(len(aw.ch))
+	for {
+		seen := aw.maxBuffered.Load()
+		if n <= seen || aw.maxBuffered.CompareAndSwap(seen, n) {
+			return
+		}
+	}
+}
+
+// offer queues msg for the worker if the channel has room, reporting whether
+// it did. It fails with io.ErrClosedPipe once the writer is closed.
+func (aw *AsyncWriter) offer(msg asyncMessage) (bool, error) {
+	aw.sendMu.RLock()
+	defer aw.sendMu.RUnlock()
+	select {
+	case <-aw.closed:
+		return false, io.ErrClosedPipe
+	default:
+	}
+
+	select {
+	case aw.ch <- msg:
+		aw.observeBuffered()
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// Err returns the error of the most recent failed write to the underlying
+// writer, or nil if no write has failed. Unlike Flush it does not reset.
+func (aw *AsyncWriter) Err() error {
+	if err := aw.lastErr.Load(); err != nil {
+		return *err
+	}
+	return nil
+}
+
+// Dropped returns the number of writes dropped so far because the buffer was
+// full. It is always zero without WithDropOnFull.
+func (aw *AsyncWriter) Dropped() uint64 {
+	return aw.dropped.Load()
+}
+
+// Buffered returns the number of writes and pending Flush, SwapWriter or
+// synchronous-write requests currently queued for the worker. It reaches the
+// bufferSize given to NewAsyncWriter when Write starts to block.
+func (aw *AsyncWriter) Buffered() int {
+	return len(aw.ch)
+}
+
+// MaxBuffered returns the highest Buffered value seen right after queueing,
+// since the writer was created.
+func (aw *AsyncWriter) MaxBuffered() int {
+	return int(aw.maxBuffered.Load())
+}
+
+// Flush blocks until everything written before the call has been handed to
+// the underlying writer. It returns the number of bytes written to the
+// underlying writer since the previous Flush, along with the first write error
+// in that span, if any.
+func (aw *AsyncWriter) Flush() (int, error) {
+	type flushResult struct {
+		n   int
+		err error
+	}
+	resultCh := make(chan flushResult, 1)
+	report := func() {
+		resultCh <- flushResult{n: aw.flushed, err: aw.flushErr}
+		aw.flushed, aw.flushErr = 0, nil
+	}
+	if err := aw.send(asyncMessage{control: report}); err != nil {
+		return 0, err
+	}
+	result := <-resultCh
+	return result.n, result.err
+}
+
+// SwapWriter redirects all subsequent writes to newW and returns the previous
+// underlying writer. Data written before the call is written to the old writer
+// first, so nothing buffered is lost. The caller owns the returned writer and
+// is responsible for closing it; Close only closes the current writer.
+func (aw *AsyncWriter) SwapWriter(newW io.Writer) (io.Writer, error) {
+	oldCh := make(chan io.Writer, 1)
+	swap := func() {
+		oldCh <- aw.writer
+		aw.writer = newW
+	}
+	if err := aw.send(asyncMessage{control: swap}); err != nil {
+		return nil, err
+	}
+	return <-oldCh, nil
+}
+
+// Close flushes any buffered data to the underlying writer, waits for the
+// writer goroutine to exit, and closes the underlying writer if it
+// implements io.Closer.
+func (aw *AsyncWriter) Close() error {
+	aw.shutdown()
+	<-aw.exited
+	return aw.closeWriter()
+}
+
+// CloseWithTimeout is Close, but gives up waiting for the writer goroutine
+// after d. Writes fail with io.ErrClosedPipe as soon as it is called. On
+// timeout it returns an error wrapping ErrCloseTimeout with the number of
+// writes that never reached the underlying writer: those still queued plus
+// any being written. The writer goroutine discards them, along with anything
+// held back by NewAsyncWriterWithFlush, once the stuck write returns. The
+// underlying writer is not closed in that case, as it may still be in use;
+// the caller owns it. A later Close waits for the goroutine and closes it.
+func (aw *AsyncWriter) CloseWithTimeout(d time.Duration) error {
+	aw.shutdown()
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-aw.exited:
+		return aw.closeWriter()
+	case <-timer.C:
+		aw.abandoned.Store(true)
+		return fmt.Errorf("%w: %d writes not flushed", ErrCloseTimeout, aw.pending.Load())
+	}
+}
+
+// shutdown stops accepting writes and lets the worker exit once it has
+// processed everything queued. Blocked senders are released before the
+// channel is closed, so no send can race with the close.
+func (aw *AsyncWriter) shutdown() {
+	aw.closeOnce.Do(func() {
+		close(aw.closed)
+		aw.sendMu.Lock()
+		close(aw.ch)
+		aw.sendMu.Unlock()
+	})
+}
+
+// closeWriter closes the underlying writer if it implements io.Closer. It must
+// only be called once the worker has exited.
+func (aw *AsyncWriter) closeWriter() error {
+	if closer, ok := aw.writer.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
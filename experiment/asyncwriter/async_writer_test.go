@@ -0,0 +1,614 @@
+package asyncwriter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// trickleWriter accepts at most max bytes per Write call.
+type trickleWriter struct {
+	buf   bytes.Buffer
+	max   int
+	calls int
+}
+
+func (w *trickleWriter) Write(p []byte) (int, error) {
+	w.calls++
+	if len(p) > w.max {
+		p = p[:w.max]
+	}
+	return w.buf.Write(p)
+}
+
+func TestAsyncWriterHandlesShortWrites(t *testing.T) {
+	sink := &trickleWriter{max: 3}
+	aw := NewAsyncWriter(sink, 16)
+
+	payload := []byte("a payload much longer than three bytes\n")
+	if _, err := aw.Write(payload); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if err := aw.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	if got := sink.buf.String(); got != string(payload) {
+		t.Errorf("underlying writer got %q, want %q", got, payload)
+	}
+	if sink.calls < len(payload)/sink.max {
+		t.Errorf("expected the payload to be written in several calls, got %d", sink.calls)
+	}
+}
+
+func TestWriteAllReportsStuckWriter(t *testing.T) {
+	sink := &trickleWriter{max: 0}
+	if _, err := writeAll(sink, []byte("data")); err != io.ErrShortWrite {
+		t.Errorf("writeAll error = %v, want %v", err, io.ErrShortWrite)
+	}
+}
+
+func TestAsyncWriterSwapWriter(t *testing.T) {
+	var first, second bytes.Buffer
+	aw := NewAsyncWriter(&first, 16)
+
+	for i := 0; i < 3; i++ {
+		fmt.Fprintf(aw, "before-%d\n", i)
+	}
+	old, err := aw.SwapWriter(&second)
+	if err != nil {
+		t.Fatalf("SwapWriter failed: %v", err)
+	}
+	if old != &first {
+		t.Errorf("SwapWriter returned %v, want the first buffer", old)
+	}
+	fmt.Fprint(aw, "after\n")
+	if err := aw.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	if got, want := first.String(), "before-0\nbefore-1\nbefore-2\n"; got != want {
+		t.Errorf("first writer got %q, want %q", got, want)
+	}
+	if got, want := second.String(), "after\n"; got != want {
+		t.Errorf("second writer got %q, want %q", got, want)
+	}
+
+	if _, err := aw.SwapWriter(&first); err != io.ErrClosedPipe {
+		t.Errorf("SwapWriter after Close error = %v, want %v", err, io.ErrClosedPipe)
+	}
+}
+
+func TestAsyncWriterSequenceNumbers(t *testing.T) {
+	var sink bytes.Buffer
+	aw := NewAsyncWriter(&sink, 4, WithSequenceNumbers())
+
+	const lines = 10
+	for i := 0; i < lines; i++ {
+		fmt.Fprintf(aw, "line-%d\n", i)
+	}
+	if err := aw.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	got := strings.Split(strings.TrimSuffix(sink.String(), "\n"), "\n")
+	if len(got) != lines {
+		t.Fatalf("got %d lines, want %d: %q", len(got), lines, sink.String())
+	}
+	for i, line := range got {
+		if want := fmt.Sprintf("%d line-%d", i+1, i); line != want {
+			t.Errorf("line %d = %q, want %q", i, line, want)
+		}
+	}
+}
+
+func TestAsyncWriterSequenceNumbersConcurrent(t *testing.T) {
+	var sink bytes.Buffer
+	aw := NewAsyncWriter(&sink, 2, WithSequenceNumbers())
+
+	const writers, lines = 16, 500
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < lines; i++ {
+				fmt.Fprintf(aw, "writer-%d line-%d\n", w, i)
+			}
+		}()
+	}
+	wg.Wait()
+	if err := aw.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	got := strings.Split(strings.TrimSuffix(sink.String(), "\n"), "\n")
+	if len(got) != writers*lines {
+		t.Fatalf("got %d lines, want %d", len(got), writers*lines)
+	}
+	for i, line := range got {
+		if want := fmt.Sprintf("%d ", i+1); !strings.HasPrefix(line, want) {
+			t.Fatalf("line %d = %q, want sequence number %d in output order", i, line, i+1)
+		}
+	}
+}
+
+func TestAsyncWriterOnFlush(t *testing.T) {
+	var sink bytes.Buffer
+	var flushed []int // Only appended to by the worker goroutine.
+	aw := NewAsyncWriter(&sink, 4, OnFlush(func(n int) { flushed = append(flushed, n) }))
+
+	lines := []string{"a\n", "bb\n", "ccc\n"}
+	for _, line := range lines {
+		if _, err := io.WriteString(aw, line); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	if err := aw.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	if len(flushed) != len(lines) {
+		t.Fatalf("OnFlush called %d times, want %d", len(flushed), len(lines))
+	}
+	for i, line := range lines {
+		if flushed[i] != len(line) {
+			t.Errorf("OnFlush call %d got %d bytes, want %d", i, flushed[i], len(line))
+		}
+	}
+
+	// Failed writes are not reported.
+	calls := 0
+	aw = NewAsyncWriter(failingWriter{err: errors.New("disk full")}, 4, OnFlush(func(int) { calls++ }))
+	io.WriteString(aw, "lost\n")
+	aw.Close()
+	if calls != 0 {
+		t.Errorf("OnFlush called %d times for failed writes, want 0", calls)
+	}
+}
+
+// lockedBuffer is a bytes.Buffer that can be read while the worker writes.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestAsyncWriterSyncEvery(t *testing.T) {
+	sink := &lockedBuffer{}
+	aw := NewAsyncWriter(sink, 16, WithSyncEvery(5))
+	defer aw.Close()
+
+	var want strings.Builder
+	for i := 0; i < 5; i++ {
+		line := fmt.Sprintf("line-%d\n", i)
+		want.WriteString(line)
+		if _, err := io.WriteString(aw, line); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+
+	// The fifth write returned only once all five reached the sink.
+	if got := sink.String(); got != want.String() {
+		t.Errorf("underlying writer got %q, want %q", got, want.String())
+	}
+}
+
+// failingWriter fails every write with err.
+type failingWriter struct {
+	err error
+}
+
+func (w failingWriter) Write(p []byte) (int, error) {
+	return 0, w.err
+}
+
+// gatedWriter signals every Write on started and then blocks it until release
+// is closed.
+type gatedWriter struct {
+	lockedBuffer
+	started chan struct{}
+	release chan struct{}
+}
+
+func (w *gatedWriter) Write(p []byte) (int, error) {
+	w.started <- struct{}{}
+	<-w.release
+	return w.lockedBuffer.Write(p)
+}
+
+func TestAsyncWriterDropOnFull(t *testing.T) {
+	sink := &gatedWriter{started: make(chan struct{}, 16), release: make(chan struct{})}
+	aw := NewAsyncWriter(sink, 1, WithDropOnFull(), WithSequenceNumbers())
+
+	// The worker holds the first write and the channel the second, so the
+	// remaining eight are dropped without blocking.
+	fmt.Fprint(aw, "msg\n")
+	<-sink.started
+	for i := 0; i < 9; i++ {
+		if n, err := fmt.Fprint(aw, "msg\n"); n != 4 || err != nil {
+			t.Fatalf("Write = %d, %v, want 4, nil", n, err)
+		}
+	}
+	if got := aw.Dropped(); got != 8 {
+		t.Errorf("Dropped = %d, want 8", got)
+	}
+
+	close(sink.release)
+	if _, err := aw.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	fmt.Fprint(aw, "msg\n")
+	if err := aw.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if got, want := sink.String(), "1 msg\n2 msg\n11 msg\n"; got != want {
+		t.Errorf("underlying writer got %q, want %q", got, want)
+	}
+
+	aw = NewAsyncWriter(io.Discard, 1)
+	defer aw.Close()
+	if got := aw.Dropped(); got != 0 {
+		t.Errorf("Dropped without WithDropOnFull = %d, want 0", got)
+	}
+}
+
+func TestAsyncWriterWriteTimeout(t *testing.T) {
+	sink := &gatedWriter{started: make(chan struct{}, 16), release: make(chan struct{})}
+	aw := NewAsyncWriter(sink, 2, WithWriteTimeout(20*time.Millisecond))
+
+	// The worker holds the first write and the channel the next two, so the
+	// fourth times out.
+	fmt.Fprint(aw, "msg-0\n")
+	<-sink.started
+	for i := 1; i < 3; i++ {
+		if _, err := fmt.Fprintf(aw, "msg-%d\n", i); err != nil {
+			t.Fatalf("Write %d failed: %v", i, err)
+		}
+	}
+	if got := aw.Buffered(); got != 2 {
+		t.Errorf("Buffered = %d, want 2", got)
+	}
+
+	start := time.Now()
+	n, err := fmt.Fprint(aw, "late\n")
+	if n != 0 || !errors.Is(err, ErrWriteTimeout) {
+		t.Fatalf("Write = %d, %v, want 0, %v", n, err, ErrWriteTimeout)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond || elapsed > time.Second {
+		t.Errorf("Write gave up after %v, want about 20ms", elapsed)
+	}
+
+	close(sink.release)
+	if err := aw.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if got, want := sink.String(), "msg-0\nmsg-1\nmsg-2\n"; got != want {
+		t.Errorf("underlying writer got %q, want %q", got, want)
+	}
+	if got := aw.Buffered(); got != 0 {
+		t.Errorf("Buffered after Close = %d, want 0", got)
+	}
+	if got := aw.MaxBuffered(); got != 2 {
+		t.Errorf("MaxBuffered = %d, want 2", got)
+	}
+}
+
+func TestAsyncWriterCloseWithTimeout(t *testing.T) {
+	sink := &lockedBuffer{}
+	aw := NewAsyncWriter(sink, 16)
+	fmt.Fprint(aw, "written\n")
+	if err := aw.CloseWithTimeout(time.Second); err != nil {
+		t.Fatalf("CloseWithTimeout failed: %v", err)
+	}
+	if got, want := sink.String(), "written\n"; got != want {
+		t.Errorf("underlying writer got %q, want %q", got, want)
+	}
+}
+
+func TestAsyncWriterCloseWithTimeoutStuckWriter(t *testing.T) {
+	stuck := &gatedWriter{started: make(chan struct{}, 16), release: make(chan struct{})}
+	aw := NewAsyncWriter(stuck, 16)
+
+	for i := 0; i < 3; i++ {
+		fmt.Fprintf(aw, "msg-%d\n", i)
+	}
+	<-stuck.started
+
+	start := time.Now()
+	err := aw.CloseWithTimeout(20 * time.Millisecond)
+	if !errors.Is(err, ErrCloseTimeout) {
+		t.Fatalf("CloseWithTimeout error = %v, want %v", err, ErrCloseTimeout)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("CloseWithTimeout took %v, want about 20ms", elapsed)
+	}
+	if !strings.Contains(err.Error(), "3 writes not flushed") {
+		t.Errorf("CloseWithTimeout error %q does not report 3 lost writes", err)
+	}
+	if _, err := fmt.Fprint(aw, "late\n"); err != io.ErrClosedPipe {
+		t.Errorf("Write after CloseWithTimeout error = %v, want %v", err, io.ErrClosedPipe)
+	}
+
+	// Once the stuck write returns, the rest is discarded.
+	close(stuck.release)
+	if err := aw.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if got, want := stuck.String(), "msg-0\n"; got != want {
+		t.Errorf("underlying writer got %q, want %q", got, want)
+	}
+}
+
+func TestAsyncWriterOnError(t *testing.T) {
+	errDisk := errors.New("disk full")
+	var errs []error // Only appended to by the worker goroutine.
+	aw := NewAsyncWriter(failingWriter{err: errDisk}, 16, OnError(func(err error) { errs = append(errs, err) }))
+	if err := aw.Err(); err != nil {
+		t.Errorf("Err before any write = %v, want nil", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		fmt.Fprintf(aw, "lost-%d\n", i)
+	}
+	if _, err := aw.Flush(); err != errDisk {
+		t.Errorf("Flush error = %v, want %v", err, errDisk)
+	}
+	if err := aw.Err(); err != errDisk {
+		t.Errorf("Err = %v, want %v", err, errDisk)
+	}
+	aw.Close()
+
+	if len(errs) != 3 {
+		t.Fatalf("OnError called %d times, want 3", len(errs))
+	}
+	for i, err := range errs {
+		if err != errDisk {
+			t.Errorf("OnError call %d got %v, want %v", i, err, errDisk)
+		}
+	}
+}
+
+func TestAsyncWriterFlushReportsBytes(t *testing.T) {
+	var sink bytes.Buffer
+	aw := NewAsyncWriter(&sink, 16)
+	defer aw.Close()
+
+	lines := []string{"first line\n", "second\n", "3\n"}
+	want := 0
+	for _, line := range lines {
+		n, err := aw.Write([]byte(line))
+		if err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+		want += n
+	}
+
+	n, err := aw.Flush()
+	if err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if n != want {
+		t.Errorf("Flush reported %d bytes, want %d", n, want)
+	}
+	if sink.Len() != want {
+		t.Errorf("underlying writer holds %d bytes after Flush, want %d", sink.Len(), want)
+	}
+
+	if n, err := aw.Flush(); n != 0 || err != nil {
+		t.Errorf("second Flush = %d, %v, want 0, nil", n, err)
+	}
+}
+
+func TestAsyncWriterFlushToFile(t *testing.T) {
+	logFile, err := os.CreateTemp(t.TempDir(), "flush-*.log")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	aw := NewAsyncWriter(logFile, 16)
+	defer aw.Close()
+
+	// Writers and flushers run concurrently; every Flush must return.
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				fmt.Fprintf(aw, "line-%d\n", i)
+				if i%10 == 0 {
+					if _, err := aw.Flush(); err != nil {
+						t.Errorf("Flush failed: %v", err)
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	fmt.Fprint(aw, "checkpoint\n")
+	if _, err := aw.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	content, err := os.ReadFile(logFile.Name())
+	if err != nil {
+		t.Fatalf("failed to read log file: %v", err)
+	}
+	if got := strings.Count(string(content), "\n"); got != 4*50+1 {
+		t.Errorf("log file has %d lines after Flush, want %d", got, 4*50+1)
+	}
+	if !strings.HasSuffix(string(content), "checkpoint\n") {
+		t.Errorf("log file does not end with the last write: %q", content)
+	}
+}
+
+func TestAsyncWriterFlushReportsWriteError(t *testing.T) {
+	errDisk := errors.New("disk full")
+	aw := NewAsyncWriter(failingWriter{err: errDisk}, 16)
+
+	fmt.Fprint(aw, "lost\n")
+	if n, err := aw.Flush(); n != 0 || err != errDisk {
+		t.Errorf("Flush = %d, %v, want 0, %v", n, err, errDisk)
+	}
+
+	aw.Close()
+	if _, err := aw.Flush(); err != io.ErrClosedPipe {
+		t.Errorf("Flush after Close error = %v, want %v", err, io.ErrClosedPipe)
+	}
+}
+
+func TestAsyncWriterWithFlushCoalesces(t *testing.T) {
+	sink := &countingWriter{w: &bytes.Buffer{}}
+	aw := NewAsyncWriterWithFlush(sink, 64, 64, 0)
+
+	var want strings.Builder
+	const lines = 20
+	for i := 0; i < lines; i++ {
+		line := fmt.Sprintf("line-%02d\n", i)
+		want.WriteString(line)
+		if _, err := io.WriteString(aw, line); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	n, err := aw.Flush()
+	if err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if n != want.Len() {
+		t.Errorf("Flush reported %d bytes, want %d", n, want.Len())
+	}
+	if err := aw.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	if got := sink.w.(*bytes.Buffer).String(); got != want.String() {
+		t.Errorf("underlying writer got %q, want %q", got, want.String())
+	}
+	// 160 bytes through a 64 byte buffer take three writes.
+	if calls := sink.calls.Load(); calls > 3 {
+		t.Errorf("underlying writer got %d writes, want at most 3", calls)
+	}
+}
+
+func TestAsyncWriterWithFlushInterval(t *testing.T) {
+	sink := &lockedBuffer{}
+	aw := NewAsyncWriterWithFlush(sink, 16, 4096, 10*time.Millisecond)
+
+	fmt.Fprint(aw, "buffered\n")
+	deadline := time.Now().Add(time.Second)
+	for sink.String() != "buffered\n" {
+		if time.Now().After(deadline) {
+			t.Fatalf("interval flush did not write the buffer, got %q", sink.String())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	fmt.Fprint(aw, "on close\n")
+	if err := aw.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if got, want := sink.String(), "buffered\non close\n"; got != want {
+		t.Errorf("underlying writer got %q, want %q", got, want)
+	}
+}
+
+// countingWriter counts the Write calls reaching the wrapped writer, i.e. the
+// write syscalls when it wraps a file.
+type countingWriter struct {
+	w     io.Writer
+	calls atomic.Int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.calls.Add(1)
+	return c.w.Write(p)
+}
+
+// benchmarkAsyncWriterWrites writes log-line sized payloads through the
+// AsyncWriter returned by newWriter and reports the writes reaching the file
+// per operation.
+func benchmarkAsyncWriterWrites(b *testing.B, newWriter func(io.Writer) *AsyncWriter) {
+	logFile, err := os.CreateTemp(b.TempDir(), "async-*.log")
+	if err != nil {
+		b.Fatalf("failed to create temp file: %v", err)
+	}
+	b.Cleanup(func() { _ = logFile.Close() })
+
+	sink := &countingWriter{w: logFile}
+	asyncWriter := newWriter(sink)
+	line := []byte("123 test_string This is a test log message.\n")
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_, _ = asyncWriter.Write(line)
+		}
+	})
+	_ = asyncWriter.Close()
+	b.ReportMetric(float64(sink.calls.Load())/float64(b.N), "writes/op")
+}
+
+// BenchmarkAsyncWriter issues one write to the file per log line.
+func BenchmarkAsyncWriter(b *testing.B) {
+	benchmarkAsyncWriterWrites(b, func(w io.Writer) *AsyncWriter {
+		return NewAsyncWriter(w, 819200)
+	})
+}
+
+// BenchmarkAsyncWriterWithFlush coalesces log lines into 64 KiB writes.
+func BenchmarkAsyncWriterWithFlush(b *testing.B) {
+	benchmarkAsyncWriterWrites(b, func(w io.Writer) *AsyncWriter {
+		return NewAsyncWriterWithFlush(w, 819200, 64<<10, 100*time.Millisecond)
+	})
+}
+
+// BenchmarkWrite measures AsyncWriter.Write from a single producer on a file.
+// The pooled case is Write as it is; the unpooled case adds the fresh copy of
+// the payload that Write used to allocate for every call, for comparison.
+func BenchmarkWrite(b *testing.B) {
+	line := []byte("123 test_string This is a test log message.\n")
+	for _, bc := range []struct {
+		name    string
+		payload func() []byte
+	}{
+		{"unpooled", func() []byte { return append([]byte(nil), line...) }},
+		{"pooled", func() []byte { return line }},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			logFile, err := os.CreateTemp(b.TempDir(), "async-*.log")
+			if err != nil {
+				b.Fatalf("failed to create temp file: %v", err)
+			}
+			b.Cleanup(func() { _ = logFile.Close() })
+			asyncWriter := NewAsyncWriter(logFile, 1024)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := asyncWriter.Write(bc.payload()); err != nil {
+					b.Fatalf("Write failed: %v", err)
+				}
+			}
+			b.StopTimer()
+			_ = asyncWriter.Close()
+		})
+	}
+}
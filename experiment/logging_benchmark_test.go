@@ -2,16 +2,16 @@ package main_test
 
 import (
 	"fmt"
-	"io"
 	"log/slog"
 	"os"
-	"sync"
 	"testing"
 	"time"
 
 	"github.com/rs/zerolog"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+
+	"go-core/experiment/asyncwriter"
 )
 
 // Common fields for a more realistic logging scenario
@@ -65,7 +65,7 @@ func BenchmarkSlogAsync(b *testing.B) {
 		b.Fatalf("failed to create temp file: %v", err)
 	}
 
-	asyncWriter := NewAsyncWriter(logFile, 819200) // 8K buffer
+	asyncWriter := asyncwriter.NewAsyncWriter(logFile, 819200) // 8K buffer
 	b.Cleanup(func() { _ = asyncWriter.Close() })
 
 	logger := slog.New(slog.NewJSONHandler(asyncWriter, nil))
@@ -118,7 +118,7 @@ func BenchmarkZerologAsync(b *testing.B) {
 		b.Fatalf("failed to create temp file: %v", err)
 	}
 
-	asyncWriter := NewAsyncWriter(logFile, 819200) // 8K buffer
+	asyncWriter := asyncwriter.NewAsyncWriter(logFile, 819200) // 8K buffer
 	b.Cleanup(func() { _ = asyncWriter.Close() })
 
 	logger := zerolog.New(asyncWriter)
@@ -179,7 +179,7 @@ func BenchmarkZapAsync(b *testing.B) {
 		b.Fatalf("failed to create temp file: %v", err)
 	}
 
-	asyncWriter := NewAsyncWriter(logFile, 819200)
+	asyncWriter := asyncwriter.NewAsyncWriter(logFile, 819200)
 	b.Cleanup(func() { _ = asyncWriter.Close() })
 
 	core := zapcore.NewCore(
@@ -197,84 +197,6 @@ func BenchmarkZapAsync(b *testing.B) {
 	})
 }
 
-// AsyncWriter provides an asynchronous, buffered writer.
-// It wraps an io.Writer and performs write operations in a separate goroutine.
-type AsyncWriter struct {
-	writer    io.Writer
-	ch        chan []byte
-	wg        sync.WaitGroup
-	closeOnce sync.Once
-	closed    chan struct{}
-}
-
-// NewAsyncWriter creates and starts a new AsyncWriter.
-// It takes an underlying io.Writer to write to and a bufferSize for the
-// internal channel.
-func NewAsyncWriter(w io.Writer, bufferSize int) *AsyncWriter {
-	if bufferSize <= 0 {
-		bufferSize = 1024 // Default buffer size
-	}
-	aw := &AsyncWriter{
-		writer: w,
-		ch:     make(chan []byte, bufferSize),
-		closed: make(chan struct{}),
-	}
-	aw.wg.Add(1)
-	go aw.run()
-	return aw
-}
-
-// run is the background worker goroutine that reads from the channel and
-// writes to the underlying writer.
-func (aw *AsyncWriter) run() {
-	defer aw.wg.Done()
-	for data := range aw.ch {
-		if _, err := aw.writer.Write(data); err != nil {
-			// In a real-world scenario, you might want a more robust error handling strategy.
-			fmt.Fprintf(os.Stderr, "AsyncWriter: write error: %v\n", err)
-		}
-	}
-}
-
-// Write sends data to the writer's buffer. It is non-blocking unless the
-// buffer is full. It makes a copy of the provided byte slice, so the caller
-// is free to reuse the original slice.
-func (aw *AsyncWriter) Write(p []byte) (int, error) {
-	select {
-	case <-aw.closed:
-		return 0, io.ErrClosedPipe
-	default:
-	}
-
-	// Make a copy of the data, as the caller might reuse the buffer p.
-	data := make([]byte, len(p))
-	copy(data, p)
-
-	select {
-	case aw.ch <- data:
-		return len(p), nil
-	case <-aw.closed:
-		return 0, io.ErrClosedPipe
-	}
-}
-
-// Close flushes any buffered data to the underlying writer, waits for the
-// writer goroutine to exit, and closes the underlying writer if it
-// implements io.Closer.
-func (aw *AsyncWriter) Close() error {
-	aw.closeOnce.Do(func() {
-		close(aw.closed)
-		close(aw.ch)
-	})
-
-	aw.wg.Wait()
-
-	if closer, ok := aw.writer.(io.Closer); ok {
-		return closer.Close()
-	}
-	return nil
-}
-
 // goos: linux
 // goarch: amd64
 // pkg: go-core/experiment
@@ -0,0 +1,23 @@
+//go:build linux
+
+package main_test
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// mmapBlockData maps an anonymous, private, read-write region of size bytes.
+func mmapBlockData(size uint64) ([]byte, error) {
+	prot, flags := syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_ANON|syscall.MAP_PRIVATE
+	addr, err := syscall.Mmap(-1, 0, int(size), prot, flags)
+	if err != nil {
+		return nil, fmt.Errorf("mmap error: %v", err)
+	}
+	return addr, nil
+}
+
+// munmapBlockData unmaps a region returned by mmapBlockData.
+func munmapBlockData(data []byte) error {
+	return syscall.Munmap(data)
+}
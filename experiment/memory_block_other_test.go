@@ -0,0 +1,19 @@
+//go:build !linux
+
+package main_test
+
+import (
+	"errors"
+)
+
+var errMmapUnsupported = errors.New("mmap is not supported on this platform")
+
+// mmapBlockData is unavailable off Linux; callers fall back to the heap.
+func mmapBlockData(size uint64) ([]byte, error) {
+	return nil, errMmapUnsupported
+}
+
+// munmapBlockData is never reached off Linux since no block is mmapped.
+func munmapBlockData(data []byte) error {
+	return nil
+}
@@ -1,8 +1,11 @@
 package main_test
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
-	"syscall"
+	"io"
+	"sync"
 	"testing"
 )
 
@@ -10,24 +13,40 @@ const (
 	MIB = 1024 * 1024
 )
 
+// ErrBlockFull is returned when a write does not fit in the remaining capacity
+// of a block.
+var ErrBlockFull = errors.New("block is full")
+
 type Block struct {
-	data      []byte
+	data []byte
+
+	// mu guards writeSeek so that concurrent appends do not overlap.
+	mu sync.Mutex
+
+	// writeSeek is the offset at which the next write lands; data[:writeSeek]
+	// holds the bytes written so far.
+	writeSeek uint64
+
+	// mmapped is true when data was obtained from mmap and must be unmapped.
+	mmapped bool
 }
 
+// AllocateBlockWithMmap allocates a block backed by an anonymous mmap region.
+// On platforms without mmap support, or if mmap fails, it falls back to a heap
+// allocation so callers always get a usable block.
 func AllocateBlockWithMmap(size uint64) (*Block, error) {
 	if size == 0 {
 		return nil, fmt.Errorf("invalid size")
 	}
 
-	prot, flags := syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_ANON|syscall.MAP_PRIVATE
-	addr, err := syscall.Mmap(-1, 0, int(size), prot, flags)
-
+	addr, err := mmapBlockData(size)
 	if err != nil {
-		return nil, fmt.Errorf("mmap error: %v", err)
+		return AllocateBlockWithoutMmap(size)
 	}
 
 	block := &Block{
-		data: addr,
+		data:    addr,
+		mmapped: true,
 	}
 
 	// we do not create channel here, as that will be created when buffer is retrieved
@@ -35,6 +54,76 @@ func AllocateBlockWithMmap(size uint64) (*Block, error) {
 	return block, nil
 }
 
+// Free releases the memory backing the block. The block must not be used after
+// Free returns.
+func (b *Block) Free() error {
+	data := b.data
+	b.data = nil
+	if !b.mmapped {
+		return nil
+	}
+	b.mmapped = false
+	return munmapBlockData(data)
+}
+
+// Slice returns a view of length bytes of the block starting at offset, without
+// copying. The returned slice aliases the block memory: writes through it are
+// visible in the block, and it must not be used after the block is freed.
+func (b *Block) Slice(offset, length uint64) ([]byte, error) {
+	size := uint64(len(b.data))
+	if offset > size || length > size-offset {
+		return nil, fmt.Errorf("slice [%d, %d) out of bounds for block of size %d", offset, offset+length, size)
+	}
+	end := offset + length
+	// Cap the view so an append by the caller cannot spill into the block.
+	return b.data[offset:end:end], nil
+}
+
+// Append copies p into the block at writeSeek and advances it. It is safe for
+// concurrent use, and each append lands in its own region of the block. The
+// append is all or nothing: if p does not fit in the remaining capacity nothing
+// is written and ErrBlockFull is returned.
+func (b *Block) Append(p []byte) (n int, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if uint64(len(p)) > uint64(len(b.data))-b.writeSeek {
+		return 0, ErrBlockFull
+	}
+	n = copy(b.data[b.writeSeek:], p)
+	b.writeSeek += uint64(n)
+	return n, nil
+}
+
+// Write implements io.Writer on top of Append.
+func (b *Block) Write(p []byte) (int, error) {
+	return b.Append(p)
+}
+
+// WriteTo implements io.WriterTo, writing the bytes written so far (up to
+// writeSeek, not the full capacity) to w without an intermediate copy.
+func (b *Block) WriteTo(w io.Writer) (int64, error) {
+	b.mu.Lock()
+	written := b.data[:b.writeSeek]
+	b.mu.Unlock()
+
+	n, err := w.Write(written)
+	return int64(n), err
+}
+
+// Reset makes the whole block available for writing again by rewinding
+// writeSeek to zero. When zero is true the underlying bytes are also wiped, so
+// a block that held sensitive content can be handed out again safely.
+func (b *Block) Reset(zero bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if zero {
+		clear(b.data)
+	}
+	b.writeSeek = 0
+}
+
 // Create a method to allocate block in go lang for a given size without MMap
 func AllocateBlockWithoutMmap(size uint64) (*Block, error) {
 	if size == 0 {
@@ -53,7 +142,7 @@ func BenchmarkCopyCostOfMmapMemoryToOneMiBBlock(b *testing.B) {
 	if err != nil {
 		b.Fatalf("failed to allocate source block: %v", err)
 	}
-	defer syscall.Munmap(srcBlock.data)
+	defer srcBlock.Free()
 
 	dst := make([]byte, MIB)
 
@@ -81,6 +170,202 @@ func BenchmarkCopyCostOfWithoutMmapMemoryToOneMiBBlock(b *testing.B) {
 	}
 }
 
+func TestAllocateBlockWithMmapIsUsable(t *testing.T) {
+	block, err := AllocateBlockWithMmap(MIB)
+	if err != nil {
+		t.Fatalf("AllocateBlockWithMmap failed: %v", err)
+	}
+	if len(block.data) != MIB {
+		t.Fatalf("block size = %d, want %d", len(block.data), MIB)
+	}
+
+	for i := range block.data {
+		block.data[i] = byte(i)
+	}
+	for i := range block.data {
+		if block.data[i] != byte(i) {
+			t.Fatalf("block.data[%d] = %d, want %d", i, block.data[i], byte(i))
+		}
+	}
+
+	if err := block.Free(); err != nil {
+		t.Errorf("Free failed: %v", err)
+	}
+	if block.data != nil {
+		t.Errorf("Free should release the block data")
+	}
+}
+
+func TestAllocateBlockWithMmapRejectsZeroSize(t *testing.T) {
+	if _, err := AllocateBlockWithMmap(0); err == nil {
+		t.Errorf("expected an error for a zero sized block")
+	}
+}
+
+func TestBlockSlice(t *testing.T) {
+	block, err := AllocateBlockWithMmap(16)
+	if err != nil {
+		t.Fatalf("AllocateBlockWithMmap failed: %v", err)
+	}
+	defer block.Free()
+	copy(block.data, "0123456789abcdef")
+
+	view, err := block.Slice(4, 6)
+	if err != nil {
+		t.Fatalf("Slice failed: %v", err)
+	}
+	if string(view) != "456789" {
+		t.Errorf("Slice(4, 6) = %q, want %q", view, "456789")
+	}
+	if cap(view) != 6 {
+		t.Errorf("Slice capacity = %d, want 6", cap(view))
+	}
+
+	// The view aliases the block rather than copying it.
+	view[0] = 'X'
+	if block.data[4] != 'X' {
+		t.Errorf("write through the view should be visible in the block")
+	}
+
+	if view, err := block.Slice(16, 0); err != nil || len(view) != 0 {
+		t.Errorf("empty slice at the end should succeed, got %q, %v", view, err)
+	}
+	for _, tc := range []struct{ offset, length uint64 }{
+		{17, 0},
+		{10, 7},
+		{0, 17},
+		{1, ^uint64(0)},
+	} {
+		if _, err := block.Slice(tc.offset, tc.length); err == nil {
+			t.Errorf("Slice(%d, %d) should fail for a 16 byte block", tc.offset, tc.length)
+		}
+	}
+}
+
+func TestBlockWriteTo(t *testing.T) {
+	block, err := AllocateBlockWithMmap(MIB)
+	if err != nil {
+		t.Fatalf("AllocateBlockWithMmap failed: %v", err)
+	}
+	defer block.Free()
+
+	for _, chunk := range []string{"hello ", "block"} {
+		if _, err := block.Write([]byte(chunk)); err != nil {
+			t.Fatalf("Write(%q) failed: %v", chunk, err)
+		}
+	}
+
+	var out bytes.Buffer
+	n, err := block.WriteTo(&out)
+	if err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	if n != 11 || out.String() != "hello block" {
+		t.Errorf("WriteTo wrote %d bytes %q, want 11 bytes %q", n, out.String(), "hello block")
+	}
+
+	var _ io.WriterTo = block
+}
+
+func TestBlockWriteFull(t *testing.T) {
+	block, err := AllocateBlockWithoutMmap(8)
+	if err != nil {
+		t.Fatalf("AllocateBlockWithoutMmap failed: %v", err)
+	}
+
+	if _, err := block.Write([]byte("12345")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if _, err := block.Write([]byte("6789")); err != ErrBlockFull {
+		t.Errorf("Write past capacity error = %v, want %v", err, ErrBlockFull)
+	}
+	if block.writeSeek != 5 {
+		t.Errorf("writeSeek = %d after a rejected write, want 5", block.writeSeek)
+	}
+}
+
+func TestBlockConcurrentAppend(t *testing.T) {
+	const (
+		writers   = 8
+		perWriter = 100
+		chunkSize = 16
+	)
+	block, err := AllocateBlockWithMmap(writers * perWriter * chunkSize)
+	if err != nil {
+		t.Fatalf("AllocateBlockWithMmap failed: %v", err)
+	}
+	defer block.Free()
+
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(id byte) {
+			defer wg.Done()
+			chunk := bytes.Repeat([]byte{id}, chunkSize)
+			for i := 0; i < perWriter; i++ {
+				if _, err := block.Append(chunk); err != nil {
+					t.Errorf("Append failed: %v", err)
+					return
+				}
+			}
+		}(byte(w + 1))
+	}
+	wg.Wait()
+
+	if block.writeSeek != uint64(len(block.data)) {
+		t.Fatalf("writeSeek = %d, want %d", block.writeSeek, len(block.data))
+	}
+
+	// Every chunk must be written by exactly one writer, with no interleaving.
+	counts := make(map[byte]int)
+	for off := 0; off < len(block.data); off += chunkSize {
+		chunk := block.data[off : off+chunkSize]
+		if !bytes.Equal(chunk, bytes.Repeat(chunk[:1], chunkSize)) {
+			t.Fatalf("chunk at offset %d is corrupted: %v", off, chunk)
+		}
+		counts[chunk[0]]++
+	}
+	for w := 1; w <= writers; w++ {
+		if counts[byte(w)] != perWriter {
+			t.Errorf("writer %d has %d chunks, want %d", w, counts[byte(w)], perWriter)
+		}
+	}
+
+	if _, err := block.Append([]byte{0}); err != ErrBlockFull {
+		t.Errorf("Append on a full block error = %v, want %v", err, ErrBlockFull)
+	}
+}
+
+func TestBlockReset(t *testing.T) {
+	block, err := AllocateBlockWithMmap(64)
+	if err != nil {
+		t.Fatalf("AllocateBlockWithMmap failed: %v", err)
+	}
+	defer block.Free()
+
+	if _, err := block.Append([]byte("secret")); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	block.Reset(false)
+	if block.writeSeek != 0 {
+		t.Errorf("writeSeek = %d after Reset(false), want 0", block.writeSeek)
+	}
+	if string(block.data[:6]) != "secret" {
+		t.Errorf("Reset(false) should leave the data untouched, got %q", block.data[:6])
+	}
+
+	if _, err := block.Append([]byte("secret")); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	block.Reset(true)
+	if block.writeSeek != 0 {
+		t.Errorf("writeSeek = %d after Reset(true), want 0", block.writeSeek)
+	}
+	if !bytes.Equal(block.data, make([]byte, len(block.data))) {
+		t.Errorf("Reset(true) should zero the data, got %q", block.data)
+	}
+}
+
 /**
 Command: go test -bench . -test.benchmem
 
@@ -0,0 +1,115 @@
+package thread_pool
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// taskBatch tracks the outcome of a group of tasks scheduled together.
+type taskBatch struct {
+	pending atomic.Int32 // Tasks that have not finished or been skipped yet.
+	failed  atomic.Bool  // Set by the first task that fails.
+	result  chan error   // Receives exactly one value: nil or the first error.
+}
+
+// done records that one task of the batch finished with err. The first error
+// is delivered straight away; nil is delivered once every task succeeded.
+func (b *taskBatch) done(err error) {
+	if err != nil && b.failed.CompareAndSwap(false, true) {
+		b.result <- err
+	}
+	if b.pending.Add(-1) == 0 && !b.failed.Load() {
+		b.result <- nil
+	}
+}
+
+// batchTask runs a member of a batch, skipping it if the batch already failed.
+// A member counts towards the batch once, even if WithRetry reruns it.
+type batchTask struct {
+	task  Task
+	batch *taskBatch
+	err   error     // Error reported by task, if it ran.
+	once  sync.Once // Guards the call to batch.done.
+}
+
+// Execute implements the Task interface for batchTask.
+func (b *batchTask) Execute() {
+	if b.batch.failed.Load() {
+		// The batch failed, maybe with this very task; cancel this run, and
+		// clear the error so the pool does not retry it.
+		b.err = nil
+		b.once.Do(func() { b.batch.done(nil) })
+		return
+	}
+
+	b.task.Execute()
+
+	if rt, ok := b.task.(ResultTask); ok {
+		b.err = rt.Err()
+	}
+	b.once.Do(func() { b.batch.done(b.err) })
+}
+
+// Err implements the ResultTask interface, reporting the wrapped task's error.
+func (b *batchTask) Err() error {
+	return b.err
+}
+
+// String labels the batch member by the task it wraps.
+func (b *batchTask) String() string {
+	return taskLabel(b.task)
+}
+
+// ScheduleBatchWithResult schedules tasks as one all-or-nothing unit. The
+// returned channel receives nil once every task has completed successfully, or
+// the first error as soon as any task fails, in which case tasks that have not
+// started yet are skipped. Failures are reported by tasks implementing
+// ResultTask; a task rejected by the pool fails the batch with the error
+// TrySchedule reports, such as ErrPoolStopped or ErrQueueFull. With WithRetry
+// a failed task still fails the batch and is not run again.
+func (t *DynamicThreadPool) ScheduleBatchWithResult(tasks []Task, urgent bool) <-chan error {
+	batch := &taskBatch{result: make(chan error, 1)}
+	if len(tasks) == 0 {
+		batch.result <- nil
+		return batch.result
+	}
+
+	batch.pending.Store(int32(len(tasks)))
+	for _, task := range tasks {
+		if err := t.TrySchedule(urgent, &batchTask{task: task, batch: batch}); err != nil {
+			batch.done(err)
+		}
+	}
+	return batch.result
+}
+
+// ScheduleBatch schedules items in order, stopping at the first one the pool
+// rejects. It returns how many were accepted, always a prefix of items, and
+// whether that was all of them, so the caller knows exactly which tasks were
+// admitted. Accepted tasks may already be running and are not withdrawn. With
+// PolicyBlock it waits for queue space like Schedule, so only a stopped pool
+// or the execute-once guard cuts the batch short.
+func (t *DynamicThreadPool) ScheduleBatch(urgent bool, items []Task) (int, bool) {
+	for i, item := range items {
+		if t.schedule(urgent, job{ctx: context.Background(), task: item}) != nil {
+			return i, false
+		}
+	}
+	return len(items), true
+}
+
+// ScheduleBatch schedules items in order like the DynamicThreadPool method of
+// the same name, returning how many were accepted and whether that was all of
+// them. Stop waits for the whole batch to be scheduled, so it is never cut
+// short by channels closing underneath it.
+func (t *StaticThreadPool) ScheduleBatch(urgent bool, items []Task) (int, bool) {
+	t.stopMu.RLock()
+	defer t.stopMu.RUnlock()
+	for i, item := range items {
+		if !t.scheduleLocked(urgent, item) {
+			return i, false
+		}
+	}
+	return len(items), true
+}
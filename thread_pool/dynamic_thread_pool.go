@@ -1,182 +1,931 @@
 package thread_pool
 
 import (
-	"log"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
-// With current implementation, normalworker can't pick the priority job.
+var (
+	// ErrPoolStopped is reported when a task is offered to a stopped pool.
+	ErrPoolStopped = errors.New("thread pool is stopped")
+	// ErrQueueFull is reported when a task is rejected because its queue has no room.
+	ErrQueueFull = errors.New("thread pool queue is full")
+	// ErrTaskInFlight is reported when the execute-once guard rejects a task
+	// pointer that is already queued or running.
+	ErrTaskInFlight = errors.New("task is already queued or running")
+	// ErrScheduleTimeout is reported when ScheduleWithTimeout gives up waiting
+	// for queue space.
+	ErrScheduleTimeout = errors.New("timed out waiting for thread pool queue space")
+	// ErrTaskPanicked is reported when a task's Execute panicked. The pool
+	// recovers the panic and keeps running.
+	ErrTaskPanicked = errors.New("task panicked")
+)
+
+// PoolState is a lifecycle phase of a DynamicThreadPool.
+type PoolState int32
+
+const (
+	// StateRunning means the pool accepts and executes tasks.
+	StateRunning PoolState = iota
+	// StateDraining means the pool rejects new tasks and is waiting for the
+	// workers it already has to finish.
+	StateDraining
+	// StateStopped means all workers have exited and the pool is torn down.
+	StateStopped
+)
+
+// String returns the name of the state.
+func (s PoolState) String() string {
+	switch s {
+	case StateRunning:
+		return "Running"
+	case StateDraining:
+		return "Draining"
+	case StateStopped:
+		return "Stopped"
+	default:
+		return "Unknown"
+	}
+}
+
+// ScheduleResult describes what happened to a single task offered to ScheduleMany.
+type ScheduleResult struct {
+	Task     Task  // The task that was offered.
+	Accepted bool  // True if the task was queued, or run by the caller under PolicyCallerRuns.
+	Err      error // Why the task was rejected; nil when accepted.
+}
+
+// PoolStats is a snapshot of pool counters, as returned by Stats.
+type PoolStats struct {
+	Scheduled uint64 // Tasks accepted since the pool was created.
+	Completed uint64 // Accepted tasks the pool is done with: executed, panicked or skipped because their context was done.
+	Rejected  uint64 // Tasks refused because the pool was stopped, the task was in flight, its context was done or its queue was full.
+
+	ActiveWorkers      uint32 // Workers currently executing tasks, as GetActiveWorkers.
+	PriorityQueueDepth int    // Tasks waiting in the priority queue.
+	NormalQueueDepth   int    // Tasks waiting in the normal queue.
+}
 
 // DynamicThreadPool manages a pool of workers created on demand,
 // with separate concurrency limits for priority and normal tasks.
-// Workers execute one task and terminate.
+// Workers keep executing queued tasks and terminate once their queues are
+// empty. Normal workers also take priority tasks, preferring them over normal
+// ones, so spare normal capacity helps clear bursts of urgent work.
 type DynamicThreadPool struct {
 	maxPriorityWorkers uint32 // Max concurrent workers for priority tasks.
 	maxNormalWorkers   uint32 // Max concurrent workers for normal tasks.
 
-	priorityCh chan Task     // Channel for high-priority tasks.
-	normalCh   chan Task     // Channel for normal-priority tasks.
+	priorityCh chan job      // Channel for high-priority tasks.
+	normalCh   chan job      // Channel for normal-priority tasks.
 	closeCh    chan struct{} // Channel to signal workers to stop.
 
 	wg sync.WaitGroup // Waits for all active workers to finish.
 
-	prioritySem chan struct{} // Semaphore limiting priority workers.
-	normalSem   chan struct{} // Semaphore limiting normal workers.
+	semMu        sync.Mutex    // Guards the semaphore fields below; held while releasing a slot.
+	prioritySem  chan struct{} // Semaphore limiting priority workers. Replaced by Resize.
+	normalSem    chan struct{} // Semaphore limiting normal workers. Replaced by Resize.
+	priorityDebt int           // Priority slots held beyond cap(prioritySem) after a shrinking Resize.
+	normalDebt   int           // Normal slots held beyond cap(normalSem) after a shrinking Resize.
+	resized      chan struct{} // Closed by Resize to wake up launches waiting on the old semaphores.
+
+	workerCount    atomic.Uint32 // Current total count of active workers.
+	saturatedSince atomic.Int64  // UnixNano when every worker slot became occupied; 0 if not saturated.
+	startOnce      sync.Once     // Ensures reserved workers are launched only once.
+	reserved       atomic.Bool   // Set once reserved priority workers are running.
+	stopOnce       sync.Once     // Ensures Stop logic runs only once.
+	isStopped      atomic.Bool   // Flag to indicate if the pool has been stopped.
+	state          atomic.Int32  // Current PoolState.
 
-	workerCount atomic.Uint32 // Current total count of active workers.
-	stopOnce    sync.Once     // Ensures Stop logic runs only once.
-	isStopped   atomic.Bool   // Flag to indicate if the pool has been stopped.
+	opts      poolOptions   // Optional behaviour configured at construction.
+	keepAlive time.Duration // How long an idle worker waits for more work; 0 means it exits at once.
+
+	idlePriority atomic.Int32 // Reusable priority workers waiting for a task.
+	idleNormal   atomic.Int32 // Reusable normal workers waiting for a task.
+
+	scheduled atomic.Uint64 // Lifetime count of accepted tasks.
+	completed atomic.Uint64 // Lifetime count of accepted tasks that finished.
+	rejected  atomic.Uint64 // Lifetime count of rejected tasks.
+	pending   atomic.Int64  // Tasks being scheduled, queued or running; zero when idle.
+	limiter   *tokenBucket  // Caps task throughput; nil when unlimited.
+	errors    *errorRing    // Recent task errors; nil unless WithRecentErrors is set.
+	panics    panicStats    // Panics recovered from tasks.
+
+	inFlightMu sync.Mutex        // Guards inFlight.
+	inFlight   map[Task]struct{} // Task pointers queued or running, when the execute-once guard is on.
 }
 
 // NewDynamicThreadPool creates a new dynamic thread pool with separate limits.
 // maxPriorityWorkers: Max concurrent goroutines processing priority tasks. Must be > 0.
 // maxNormalWorkers: Max concurrent goroutines processing normal tasks. Must be > 0.
-func NewDynamicThreadPool(maxPriorityWorkers, maxNormalWorkers uint32) *DynamicThreadPool {
+// opts: Optional settings, e.g. WithQuiet().
+func NewDynamicThreadPool(maxPriorityWorkers, maxNormalWorkers uint32, opts ...Option) *DynamicThreadPool {
+	options := newPoolOptions(opts)
 	if maxPriorityWorkers == 0 {
-		log.Println("DynamicThreadPool: maxPriorityWorkers cannot be zero")
+		options.errorf("DynamicThreadPool: maxPriorityWorkers cannot be zero\n")
 		return nil
 	}
 	if maxNormalWorkers == 0 {
-		log.Println("DynamicThreadPool: maxNormalWorkers cannot be zero")
+		options.errorf("DynamicThreadPool: maxNormalWorkers cannot be zero\n")
 		return nil
 	}
-
-	log.Printf("DynamicThreadPool: Creating with maxPriorityWorkers: %d, maxNormalWorkers: %d\n",
+	if name := options.unsupported("WithExecuteOnceGuard", "WithReservedPriorityWorkers", "WithRateLimit",
+		"WithInlineUrgentFallback", "WithRecentErrors", "WithQueueCapacity", "WithRejectionPolicy", "WithRetry"); name != "" {
+		options.errorf("DynamicThreadPool: %s is not supported\n", name)
+		return nil
+	}
+	if options.reservedPriority > maxPriorityWorkers {
+		options.errorf("DynamicThreadPool: reserved priority workers (%d) cannot exceed maxPriorityWorkers (%d)\n",
+			options.reservedPriority, maxPriorityWorkers)
+		return nil
+	}
+	options.debugf("DynamicThreadPool: Creating with maxPriorityWorkers: %d, maxNormalWorkers: %d\n",
 		maxPriorityWorkers, maxNormalWorkers)
 
-	return &DynamicThreadPool{
+	priorityQueue, normalQueue := int(maxPriorityWorkers*2), int(maxNormalWorkers*10)
+	if options.priorityQueue > 0 {
+		priorityQueue = options.priorityQueue
+	}
+	if options.normalQueue > 0 {
+		normalQueue = options.normalQueue
+	}
+
+	t := &DynamicThreadPool{
 		maxPriorityWorkers: maxPriorityWorkers,
 		maxNormalWorkers:   maxNormalWorkers,
-		// Buffer channels appropriately. Sizes are examples.
-		priorityCh:  make(chan Task, maxPriorityWorkers*2), // Example buffer size
-		normalCh:    make(chan Task, maxNormalWorkers*10),  // Example buffer size
-		closeCh:     make(chan struct{}),
-		prioritySem: make(chan struct{}, maxPriorityWorkers), // Semaphore for priority tasks
-		normalSem:   make(chan struct{}, maxNormalWorkers),   // Semaphore for normal tasks
+		priorityCh:         make(chan job, priorityQueue),
+		normalCh:           make(chan job, normalQueue),
+		closeCh:            make(chan struct{}),
+		prioritySem:        make(chan struct{}, maxPriorityWorkers), // Semaphore for priority tasks
+		normalSem:          make(chan struct{}, maxNormalWorkers),   // Semaphore for normal tasks
+		resized:            make(chan struct{}),
+		opts:               options,
+		limiter:            options.newLimiter(),
+		errors:             newErrorRing(options.recentErrors),
+		inFlight:           make(map[Task]struct{}),
 	}
+	return t
 }
 
-// Start prepares the pool to accept tasks. No workers are started initially.
+// NewDynamicThreadPoolReusable creates a dynamic pool whose workers are reused:
+// a worker that runs out of tasks waits up to keepAlive for more before it
+// exits and gives up its slot. This keeps the on-demand footprint of the
+// dynamic pool while amortising goroutine creation over bursts of tasks.
+// Workers only count as active while executing. keepAlive must be > 0.
+func NewDynamicThreadPoolReusable(maxPriorityWorkers, maxNormalWorkers uint32, keepAlive time.Duration, opts ...Option) *DynamicThreadPool {
+	if keepAlive <= 0 {
+		options := newPoolOptions(opts)
+		options.errorf("DynamicThreadPool: keepAlive must be positive\n")
+		return nil
+	}
+	t := NewDynamicThreadPool(maxPriorityWorkers, maxNormalWorkers, opts...)
+	if t != nil {
+		t.keepAlive = keepAlive
+	}
+	return t
+}
+
+// Start prepares the pool to accept tasks. Apart from any reserved priority
+// workers, no workers are started initially.
 func (t *DynamicThreadPool) Start() {
-	log.Println("DynamicThreadPool: Started. Workers will be created per task.")
+	t.startOnce.Do(func() {
+		if t.isStopped.Load() {
+			return
+		}
+		for i := uint32(0); i < t.opts.reservedPriority; i++ {
+			prioritySem, _, _ := t.semaphores()
+			prioritySem <- struct{}{} // Reserved workers fit within maxPriorityWorkers.
+			t.markSlotAcquired()
+			t.wg.Add(1)
+			go t.reservedPriorityWorkerTask()
+		}
+		t.reserved.Store(t.opts.reservedPriority > 0)
+		register(t)
+	})
+	t.opts.debugf("DynamicThreadPool: Started with %d reserved priority workers. Other workers will be created per task.\n",
+		t.opts.reservedPriority)
 }
 
 // Schedule adds a task to the appropriate queue and attempts to launch
 // a corresponding worker if the concurrency limit for that type allows.
-// Returns false if the pool is stopped, true otherwise.
+// Returns false if the pool is stopped or the task was dropped because its
+// queue is full, true otherwise. Use TrySchedule to learn why.
 func (t *DynamicThreadPool) Schedule(urgent bool, item Task) bool {
+	return t.ScheduleCtx(context.Background(), urgent, item)
+}
+
+// ScheduleFunc schedules fn like Schedule, wrapped in a FuncTask.
+func (t *DynamicThreadPool) ScheduleFunc(urgent bool, fn func()) bool {
+	return t.Schedule(urgent, FuncTask(fn))
+}
+
+// TrySchedule schedules item like Schedule and reports why it was rejected:
+// ErrPoolStopped, ErrTaskInFlight, or ErrQueueFull when PolicyDropNewest
+// dropped it. It returns nil if item was accepted.
+func (t *DynamicThreadPool) TrySchedule(urgent bool, item Task) error {
+	return t.schedule(urgent, job{ctx: context.Background(), task: item})
+}
+
+// ScheduleWithTimeout schedules item like Schedule, but waits at most timeout
+// for room in a full queue, returning ErrScheduleTimeout after that. A zero
+// timeout means try once and don't block, like a non-blocking channel send.
+// Stopping the pool during the wait returns ErrPoolStopped straight away. Once
+// item is queued, a worker is launched for it exactly as Schedule does. Pools
+// with a rejection policy other than PolicyBlock never wait for queue space,
+// so the policy decides instead.
+func (t *DynamicThreadPool) ScheduleWithTimeout(urgent bool, item Task, timeout time.Duration) (bool, error) {
+	if timeout < 0 {
+		timeout = 0
+	}
+	err := t.scheduleWithin(urgent, job{ctx: context.Background(), task: item}, timeout)
+	return err == nil, err
+}
+
+// ScheduleCtx schedules item like Schedule, tied to ctx. If ctx is done by the
+// time a worker picks item up, Execute is skipped and the worker moves on.
+// ScheduleCtx also stops waiting for queue space once ctx is done. It returns
+// false if the pool is stopped or ctx is done before item is queued.
+func (t *DynamicThreadPool) ScheduleCtx(ctx context.Context, urgent bool, item Task) bool {
+	return t.schedule(urgent, job{ctx: ctx, task: item}) == nil
+}
+
+// ScheduleWithFuture schedules item like Schedule and returns a future that
+// completes when item's Execute returns. If the pool rejects item, the future
+// is already complete and its Err reports why.
+func (t *DynamicThreadPool) ScheduleWithFuture(urgent bool, item Task) *TaskFuture {
+	future := newTaskFuture()
+	if err := t.schedule(urgent, job{ctx: context.Background(), task: item, future: future}); err != nil {
+		future.complete(err)
+	}
+	return future
+}
+
+// Submit schedules item like Schedule and waits until a worker starts running
+// it, so a nil error guarantees the task is executed. It returns
+// ErrPoolStopped straight away if the pool is stopped, or once the pool has
+// stopped with item still queued, as Stop discards queued tasks. Other
+// rejections are reported as by TrySchedule. Submit returns as soon as item
+// starts, without waiting for it to finish.
+func (t *DynamicThreadPool) Submit(urgent bool, item Task) error {
+	started := newTaskFuture()
+	if err := t.schedule(urgent, job{ctx: context.Background(), task: item, started: started}); err != nil {
+		return err
+	}
+	select {
+	case <-started.Done():
+		return nil
+	case <-t.closeCh:
+	}
+
+	// A worker may still take item until every worker has exited.
+	ticker := time.NewTicker(idlePollInterval)
+	defer ticker.Stop()
+	for t.State() != StateStopped {
+		select {
+		case <-started.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+	select {
+	case <-started.Done():
+		return nil
+	default:
+		return ErrPoolStopped
+	}
+}
+
+// schedule queues j and launches a worker for it. It returns ErrPoolStopped,
+// ErrTaskInFlight, ErrQueueFull or the error of j.ctx if j was rejected.
+func (t *DynamicThreadPool) schedule(urgent bool, j job) error {
+	return t.scheduleWithin(urgent, j, -1)
+}
+
+// scheduleWithin is schedule, waiting at most timeout for queue space, or as
+// long as needed if timeout is negative. It also returns ErrScheduleTimeout.
+func (t *DynamicThreadPool) scheduleWithin(urgent bool, j job, timeout time.Duration) error {
+	t.pending.Add(1) // Before queueing, so WaitIdle cannot miss the task.
+	err := t.enqueue(urgent, j, timeout)
+	if err != nil {
+		t.pending.Add(-1)
+		t.rejected.Add(1)
+	}
+	return err
+}
+
+// enqueue does the work of scheduleWithin apart from counting rejections.
+func (t *DynamicThreadPool) enqueue(urgent bool, j job, timeout time.Duration) error {
 	if t.isStopped.Load() {
 		// log.Println("DynamicThreadPool: Cannot schedule task on stopped pool") // Optional: Reduce log noise
-		return false
+		return ErrPoolStopped
+	}
+	if err := j.ctx.Err(); err != nil {
+		return err
+	}
+	if !t.acquireTask(j.task) {
+		return ErrTaskInFlight
+	}
+	j.urgent = urgent
+	if urgent && t.opts.inlineUrgent {
+		return t.scheduleUrgentOrRunInline(j)
 	}
 
+	queue, launch := t.normalCh, t.tryLaunchNormalWorker
 	if urgent {
-		// Try to queue priority task
+		queue, launch = t.priorityCh, t.tryLaunchPriorityWorker
+	}
+
+	if t.opts.rejectionPolicy != PolicyBlock {
 		select {
-		case t.priorityCh <- item:
-			t.tryLaunchPriorityWorker() // Attempt to launch a PRIORITY worker
-			return true
-		case <-t.closeCh:
-			log.Println("DynamicThreadPool: Pool stopped while trying to schedule priority task")
-			return false
+		case queue <- j:
+			t.scheduled.Add(1)
+			launch(j.task)
+			return nil
+		default:
 		}
-	} else {
-		// Try to queue normal task
+		return t.overflow(j)
+	}
+
+	var expired <-chan time.Time // Stays nil, and never ready, without a timeout.
+	if timeout == 0 {
 		select {
-		case t.normalCh <- item:
-			t.tryLaunchNormalWorker() // Attempt to launch a NORMAL worker
-			return true
-		case <-t.closeCh:
-			log.Println("DynamicThreadPool: Pool stopped while trying to schedule normal task")
-			return false
+		case queue <- j:
+			t.scheduled.Add(1)
+			launch(j.task)
+			return nil
+		default:
+			t.releaseTask(j.task)
+			return ErrScheduleTimeout
+		}
+	} else if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	select {
+	case queue <- j:
+		t.scheduled.Add(1)
+		launch(j.task) // Attempt to launch a worker for the task
+		return nil
+	case <-expired:
+		t.releaseTask(j.task)
+		return ErrScheduleTimeout
+	case <-t.closeCh:
+		t.opts.errorf("DynamicThreadPool: Pool stopped while trying to schedule %s task\n", priorityName(urgent))
+		t.releaseTask(j.task)
+		return ErrPoolStopped
+	case <-j.ctx.Done():
+		t.releaseTask(j.task)
+		return j.ctx.Err()
+	}
+}
+
+// overflow handles j, whose queue is full, according to the rejection policy:
+// under PolicyCallerRuns j runs on the calling goroutine, otherwise it is
+// rejected with ErrQueueFull.
+func (t *DynamicThreadPool) overflow(j job) error {
+	if t.opts.rejectionPolicy == PolicyCallerRuns {
+		t.scheduled.Add(1)
+		t.runTask(j)
+		return nil
+	}
+	t.releaseTask(j.task)
+	return ErrQueueFull
+}
+
+// ScheduleMany offers every item to the queue selected by urgent and reports the
+// disposition of each one, in the same order as items. Unlike Schedule it never
+// waits for queue space: items that do not fit are handled by the rejection
+// policy, and under PolicyBlock rejected with ErrQueueFull like under
+// PolicyDropNewest, so callers can dead-letter them. With
+// WithInlineUrgentFallback, urgent items are scheduled as by Schedule. Workers
+// for the queued items are launched once all items have been offered.
+func (t *DynamicThreadPool) ScheduleMany(urgent bool, items []Task) []ScheduleResult {
+	queue := t.normalCh
+	if urgent {
+		queue = t.priorityCh
+	}
+
+	results := make([]ScheduleResult, len(items))
+	accepted := 0
+	for i, item := range items {
+		results[i].Task = item
+		if t.isStopped.Load() {
+			results[i].Err = ErrPoolStopped
+		} else if !t.acquireTask(item) {
+			results[i].Err = ErrTaskInFlight
+		} else {
+			t.pending.Add(1)
+			j := job{ctx: context.Background(), task: item, urgent: urgent}
+			var err error
+			if urgent && t.opts.inlineUrgent {
+				err = t.scheduleUrgentOrRunInline(j)
+			} else {
+				select {
+				case queue <- j:
+					t.scheduled.Add(1)
+					accepted++
+				default:
+					err = t.overflow(j)
+				}
+			}
+			if err != nil {
+				t.pending.Add(-1)
+			}
+			results[i].Accepted = err == nil
+			results[i].Err = err
+		}
+		if results[i].Err != nil {
+			t.rejected.Add(1)
+		}
+	}
+
+	for i := 0; i < accepted; i++ {
+		if urgent {
+			t.tryLaunchPriorityWorker(nil)
+		} else {
+			t.tryLaunchNormalWorker(nil)
+		}
+	}
+	return results
+}
+
+// acquireTask registers item with the execute-once guard. It returns false if the
+// same task pointer is already queued or running. Without the guard, or for
+// tasks that are not pointers, it always succeeds.
+func (t *DynamicThreadPool) acquireTask(item Task) bool {
+	if !t.opts.executeOnce || reflect.ValueOf(item).Kind() != reflect.Pointer {
+		return true
+	}
+
+	t.inFlightMu.Lock()
+	defer t.inFlightMu.Unlock()
+	if _, ok := t.inFlight[item]; ok {
+		return false
+	}
+	t.inFlight[item] = struct{}{}
+	return true
+}
+
+// releaseTask removes item from the execute-once guard.
+func (t *DynamicThreadPool) releaseTask(item Task) {
+	if !t.opts.executeOnce {
+		return
+	}
+
+	t.inFlightMu.Lock()
+	delete(t.inFlight, item)
+	t.inFlightMu.Unlock()
+}
+
+// runTask waits for the rate limiter, executes the task of j, releases its
+// execute-once guard, if any, and completes its future. The task is skipped if
+// its context is already done, in which case the future reports the context
+// error. A failed task with retries left is rescheduled instead, and its
+// future completes after the last run.
+func (t *DynamicThreadPool) runTask(j job) {
+	retry := false
+	defer t.pending.Add(-1)
+	defer t.completed.Add(1)
+	defer func() {
+		if retry {
+			t.retryLater(j) // Once the guard is released, but still pending.
 		}
+	}()
+	defer t.releaseTask(j.task)
+	j.started.complete(nil)
+	if err := j.ctx.Err(); err != nil {
+		t.opts.debugf("DynamicThreadPool: Skipping task, its context is done: %v\n", err)
+		j.future.complete(err)
+		return
+	}
+	defer func() {
+		// Recover before the worker's own deferred cleanup releases its slot,
+		// so one bad task cannot take down the process.
+		var err error
+		if r := recover(); r != nil {
+			t.opts.errorf("DynamicThreadPool: Recovered panic in task %T: %v\n", j.task, r)
+			t.panics.record(r)
+			err = fmt.Errorf("%w: %v", ErrTaskPanicked, r)
+			if t.opts.panicHandler != nil {
+				t.opts.panicHandler(r)
+			}
+		}
+		if !retry {
+			j.future.complete(err)
+		}
+	}()
+	t.limiter.wait()
+	id, traced := taskID(j.task)
+	if traced {
+		t.opts.debugf("DynamicThreadPool: Starting %s task %q\n", priorityName(j.urgent), id)
+	}
+	j.task.Execute()
+	if traced {
+		t.opts.debugf("DynamicThreadPool: Finished %s task %q\n", priorityName(j.urgent), id)
+	}
+	t.errors.record(j.task)
+	retry = failed(j.task) && t.opts.canRetry(j.attempt)
+}
+
+// priorityName names the queue selected by urgent, for logs.
+func priorityName(urgent bool) string {
+	if urgent {
+		return "priority"
 	}
+	return "normal"
 }
 
-// tryLaunchPriorityWorker attempts to acquire the priority semaphore and start a priority worker.
-func (t *DynamicThreadPool) tryLaunchPriorityWorker() {
+// retryLater schedules j to run again, on the queue it came from, after the
+// backoff for its attempt. The retry counts as pending while it waits.
+func (t *DynamicThreadPool) retryLater(j job) {
+	delay := t.opts.retryDelay(j.attempt)
+	j.attempt++
+	t.opts.debugf("DynamicThreadPool: Retrying task %T in %v, retry %d of %d\n", j.task, delay, j.attempt, t.opts.maxRetries)
+	t.pending.Add(1)
+	time.AfterFunc(delay, func() {
+		defer t.pending.Add(-1)
+		if err := t.schedule(j.urgent, j); err != nil {
+			t.opts.errorf("DynamicThreadPool: Dropping retry %d of task %T: %v\n", j.attempt, j.task, err)
+			j.future.complete(err)
+		}
+	})
+}
+
+// tryLaunchPriorityWorker starts a worker for a queued priority task. It
+// prefers a priority slot, falls back to a free normal slot, and otherwise waits
+// for whichever frees up first.
+func (t *DynamicThreadPool) tryLaunchPriorityWorker(item Task) {
 	if t.isStopped.Load() { // Check if stopped before trying to launch
 		return
 	}
+	if t.keepAlive > 0 && int(t.idlePriority.Load()+t.idleNormal.Load()) >= len(t.priorityCh) {
+		return // Idle reusable workers will take the queued tasks.
+	}
+
+	prioritySem, normalSem, resized := t.semaphores()
+	select {
+	case prioritySem <- struct{}{}:
+		t.launchPriorityWorker(item)
+		return
+	default:
+	}
+	select {
+	case normalSem <- struct{}{}:
+		t.launchNormalWorker(item)
+		return
+	default:
+	}
+
+	if t.reserved.Load() || !t.waitsForSlot() {
+		// The reserved workers are always listening, and otherwise running
+		// workers keep taking tasks, so there is no need to wait for a slot.
+		return
+	}
+	for {
+		select {
+		case prioritySem <- struct{}{}:
+			t.launchPriorityWorker(item)
+			return
+		case normalSem <- struct{}{}:
+			t.launchNormalWorker(item)
+			return
+		case <-resized:
+			prioritySem, normalSem, resized = t.semaphores()
+		}
+	}
+}
 
-	// Acquired priority semaphore, start a new priority worker goroutine
-	t.prioritySem <- struct{}{}
+// launchPriorityWorker starts a priority worker in a priority semaphore slot
+// the caller has already acquired. item is the task that caused the launch,
+// named in the log if it is an IdentifiableTask, or nil.
+func (t *DynamicThreadPool) launchPriorityWorker(item Task) {
+	t.markSlotAcquired()
 	t.workerCount.Add(1)
 	t.wg.Add(1)
 	go t.priorityWorkerTask()
-	log.Printf("DynamicThreadPool: Launched priority worker. Active count: %d\n", t.workerCount.Load())
+	t.opts.debugf("DynamicThreadPool: Launched priority worker%s. Active count: %d\n", forTask(item), t.workerCount.Load())
+}
+
+// scheduleUrgentOrRunInline queues j and launches a priority worker for it if
+// a priority slot is free right now. Otherwise j runs on the calling goroutine
+// before returning.
+func (t *DynamicThreadPool) scheduleUrgentOrRunInline(j job) error {
+	prioritySem, _, _ := t.semaphores()
+	select {
+	case prioritySem <- struct{}{}:
+	default:
+		t.scheduled.Add(1)
+		t.runTask(j)
+		return nil
+	}
+
+	select {
+	case t.priorityCh <- j:
+		t.scheduled.Add(1)
+		t.launchPriorityWorker(j.task)
+		return nil
+	case <-t.closeCh:
+		t.releasePrioritySlot()
+		t.releaseTask(j.task)
+		return ErrPoolStopped
+	}
 }
 
 // tryLaunchNormalWorker attempts to acquire the normal semaphore and start a normal worker.
-func (t *DynamicThreadPool) tryLaunchNormalWorker() {
+func (t *DynamicThreadPool) tryLaunchNormalWorker(item Task) {
 	if t.isStopped.Load() { // Check if stopped before trying to launch
 		return
 	}
-	// Acquired normal semaphore, start a new normal worker goroutine
-	t.normalSem <- struct{}{}
+	if t.keepAlive > 0 && int(t.idleNormal.Load()) >= len(t.normalCh)+len(t.priorityCh) {
+		return // Idle reusable workers will take the queued tasks.
+	}
+	if t.waitsForSlot() {
+		t.acquireNormalSlot()
+	} else {
+		// Running workers keep taking tasks, so only add one if a slot is
+		// free right now.
+		_, normalSem, _ := t.semaphores()
+		select {
+		case normalSem <- struct{}{}:
+		default:
+			return
+		}
+	}
+	t.launchNormalWorker(item)
+}
+
+// semaphores returns the current worker semaphores and the channel Resize
+// closes when it replaces them. A goroutine waiting for a slot must also wait
+// on resized and start over with the new semaphores once it is closed.
+func (t *DynamicThreadPool) semaphores() (prioritySem, normalSem, resized chan struct{}) {
+	t.semMu.Lock()
+	defer t.semMu.Unlock()
+	return t.prioritySem, t.normalSem, t.resized
+}
+
+// acquireNormalSlot waits for a free normal worker slot and takes it.
+func (t *DynamicThreadPool) acquireNormalSlot() {
+	for {
+		_, normalSem, resized := t.semaphores()
+		select {
+		case normalSem <- struct{}{}:
+			return
+		case <-resized:
+		}
+	}
+}
+
+// releasePrioritySlot hands back a priority worker slot. Slots taken before
+// a Resize are released to the current semaphore, after paying off any debt
+// left by shrinking it.
+func (t *DynamicThreadPool) releasePrioritySlot() {
+	t.semMu.Lock()
+	defer t.semMu.Unlock()
+	if t.priorityDebt > 0 {
+		t.priorityDebt--
+		return
+	}
+	<-t.prioritySem
+}
+
+// releaseNormalSlot hands back a normal worker slot, like releasePrioritySlot.
+func (t *DynamicThreadPool) releaseNormalSlot() {
+	t.semMu.Lock()
+	defer t.semMu.Unlock()
+	if t.normalDebt > 0 {
+		t.normalDebt--
+		return
+	}
+	<-t.normalSem
+}
+
+// waitsForSlot reports whether launching a worker waits for a free slot. It
+// does not for reusable pools or when a rejection policy is set, so Schedule
+// never blocks on a busy pool; queued tasks are then left to running workers
+// and to relaunchIfPending.
+func (t *DynamicThreadPool) waitsForSlot() bool {
+	return t.keepAlive <= 0 && t.opts.rejectionPolicy == PolicyBlock
+}
+
+// launchNormalWorker starts a normal worker in a normal semaphore slot the
+// caller has already acquired. item is as for launchPriorityWorker.
+func (t *DynamicThreadPool) launchNormalWorker(item Task) {
+	t.markSlotAcquired()
 	t.workerCount.Add(1)
 	t.wg.Add(1)
 	go t.normalWorkerTask()
-	log.Printf("DynamicThreadPool: Launched normal worker. Active count: %d\n", t.workerCount.Load())
+	t.opts.debugf("DynamicThreadPool: Launched normal worker%s. Active count: %d\n", forTask(item), t.workerCount.Load())
+}
+
+// markSlotAcquired starts the saturation clock if the worker slot just acquired
+// was the last free one.
+func (t *DynamicThreadPool) markSlotAcquired() {
+	prioritySem, normalSem, _ := t.semaphores()
+	if len(prioritySem) == cap(prioritySem) && len(normalSem) == cap(normalSem) {
+		t.saturatedSince.CompareAndSwap(0, time.Now().UnixNano())
+	}
+}
+
+// markSlotReleased stops the saturation clock. It must be called before the
+// slot is handed back, so that a launch taking the slot right away can start a
+// fresh saturation period.
+func (t *DynamicThreadPool) markSlotReleased() {
+	t.saturatedSince.Store(0)
 }
 
-// priorityWorkerTask fetches and executes exactly one task from the priority queue.
+// priorityWorkerTask executes tasks from the priority queue until it is empty
+// or the pool stops.
 func (t *DynamicThreadPool) priorityWorkerTask() {
 	// Ensure semaphore is released, WG is decremented, and count updated when done.
 	defer func() {
-		<-t.prioritySem               // Release PRIORITY semaphore slot
+		t.markSlotReleased()
+		t.releasePrioritySlot()       // Release PRIORITY semaphore slot
 		t.workerCount.Add(^uint32(0)) // Decrement total worker count
+		t.relaunchIfPending()
 		t.wg.Done()
-		log.Printf("DynamicThreadPool: Priority worker finished. Active count: %d\n", t.workerCount.Load())
+		t.opts.debugf("DynamicThreadPool: Priority worker finished. Active count: %d\n", t.workerCount.Load())
 	}()
 
-	// This worker tries to grab exactly one priority task.
-	select {
-	case <-t.closeCh: // Highest priority: Shutdown signal
-		// log.Println("DynamicThreadPool: Priority worker received stop signal before processing task.")
-		return // Exit immediately
-
-	case task, ok := <-t.priorityCh: // Read ONLY from priority channel
+	for {
+		task, ok := t.nextJob(true)
 		if !ok {
-			// log.Println("DynamicThreadPool: Priority channel closed while priority worker waiting, exiting.")
-			return // Channel closed
+			return
+		}
+		t.runTask(task)
+	}
+}
+
+// reservedPriorityWorkerTask executes priority tasks until the pool stops. It
+// holds its priority semaphore slot for its whole lifetime but only counts as
+// an active worker while executing a task.
+func (t *DynamicThreadPool) reservedPriorityWorkerTask() {
+	defer func() {
+		t.markSlotReleased()
+		t.releasePrioritySlot() // Release PRIORITY semaphore slot
+		t.wg.Done()
+	}()
+
+	for {
+		select {
+		case <-t.closeCh:
+			return
+		case task, ok := <-t.priorityCh:
+			if !ok {
+				return
+			}
+			t.workerCount.Add(1)
+			t.runTask(task)
+			t.workerCount.Add(^uint32(0))
 		}
-		task.Execute()
-		return // Worker terminates after executing one task
 	}
 }
 
-// normalWorkerTask fetches and executes exactly one task from the normal queue.
+// normalWorkerTask executes tasks until both queues are empty or the pool
+// stops. Queued priority tasks are taken before normal ones.
 func (t *DynamicThreadPool) normalWorkerTask() {
 	// Ensure semaphore is released, WG is decremented, and count updated when done.
 	defer func() {
-		<-t.normalSem                 // Release NORMAL semaphore slot
+		t.markSlotReleased()
+		t.releaseNormalSlot()         // Release NORMAL semaphore slot
 		t.workerCount.Add(^uint32(0)) // Decrement total worker count
+		t.relaunchIfPending()
 		t.wg.Done()
-		log.Printf("DynamicThreadPool: Normal worker finished. Active count: %d\n", t.workerCount.Load())
+		t.opts.debugf("DynamicThreadPool: Normal worker finished. Active count: %d\n", t.workerCount.Load())
 	}()
 
-	// This worker tries to grab exactly one normal task.
+	for {
+		task, ok := t.nextJob(false)
+		if !ok {
+			return
+		}
+		t.runTask(task)
+	}
+}
+
+// nextJob returns the next task for a worker, preferring priority tasks.
+// Priority workers (priorityOnly) never take normal tasks. If the queues are
+// empty it returns false straight away, or, for reusable workers, once no task
+// arrived within keepAlive. It also returns false once the pool stops. An
+// idle reusable worker does not count as active while it waits.
+func (t *DynamicThreadPool) nextJob(priorityOnly bool) (job, bool) {
 	select {
 	case <-t.closeCh: // Highest priority: Shutdown signal
-		// log.Println("DynamicThreadPool: Normal worker received stop signal before processing task.")
-		return // Exit immediately
-	case task, ok := <-t.normalCh: // Read ONLY from normal channel
-		if !ok {
-			// log.Println("DynamicThreadPool: Normal channel closed while normal worker waiting, exiting.")
-			return // Channel closed
+		return job{}, false
+	default:
+	}
+
+	select {
+	case task, ok := <-t.priorityCh:
+		return task, ok
+	default:
+	}
+
+	var normalCh chan job // Stays nil, and never ready, for priority workers.
+	if !priorityOnly {
+		normalCh = t.normalCh
+		select {
+		case task, ok := <-normalCh:
+			return task, ok
+		default:
 		}
-		task.Execute()
-		return // Worker terminates after executing one task
+	}
+
+	if t.keepAlive <= 0 {
+		return job{}, false // Nothing left to do
+	}
+
+	idle := &t.idleNormal
+	if priorityOnly {
+		idle = &t.idlePriority
+	}
+	idle.Add(1)
+	defer idle.Add(-1)
+	t.workerCount.Add(^uint32(0))
+	defer t.workerCount.Add(1)
+	timer := time.NewTimer(t.keepAlive)
+	defer timer.Stop()
+
+	select {
+	case <-t.closeCh:
+		return job{}, false
+	case task, ok := <-t.priorityCh:
+		return task, ok
+	case task, ok := <-normalCh:
+		return task, ok
+	case <-timer.C:
+		return job{}, false
+	}
+}
+
+// relaunchIfPending is called by an exiting worker once its slot is released.
+// Unless launches wait for a slot, the pool does not launch a worker while
+// others are busy or idle, counting on the existing workers instead, so a task
+// queued just as this worker gave up would otherwise be stranded.
+func (t *DynamicThreadPool) relaunchIfPending() {
+	if t.waitsForSlot() || t.isStopped.Load() {
+		return
+	}
+	if len(t.priorityCh) > 0 {
+		t.tryLaunchPriorityWorker(nil)
+	} else if len(t.normalCh) > 0 {
+		t.tryLaunchNormalWorker(nil)
 	}
 }
 
 // Stop signals workers to terminate and waits for currently executing workers to finish.
+// Tasks still queued are not executed.
 func (t *DynamicThreadPool) Stop() {
+	t.shutdown(false)
+}
+
+// StopContext stops the pool like Stop, but gives up waiting for executing
+// tasks once ctx is done and returns its error. The pool is marked stopped
+// either way, so new tasks are rejected. Go cannot kill a goroutine, so after
+// a timeout the workers of hung tasks are still running; the shutdown
+// completes in the background once they return, and a later Stop waits for
+// that.
+func (t *DynamicThreadPool) StopContext(ctx context.Context) error {
+	t.isStopped.Store(true)
+	t.state.CompareAndSwap(int32(StateRunning), int32(StateDraining))
+
+	done := make(chan struct{})
+	go func() {
+		t.shutdown(false)
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Drain stops the pool like Stop, but first executes every task that is
+// already queued. New tasks are rejected as soon as Drain is called. Drain is
+// idempotent and, like Stop, does nothing once the pool has stopped; a Stop
+// called while Drain is running waits for the drain to finish.
+func (t *DynamicThreadPool) Drain() {
+	t.shutdown(true)
+}
+
+// shutdown implements Stop and Drain, emptying the queues first if drain is
+// set.
+func (t *DynamicThreadPool) shutdown(drain bool) {
 	t.stopOnce.Do(func() {
-		log.Println("DynamicThreadPool: Stopping...")
+		t.opts.debugf("DynamicThreadPool: Stopping...\n")
 		t.isStopped.Store(true) // Mark as stopped first
+		t.state.Store(int32(StateDraining))
+
+		if drain {
+			t.drainQueues()
+		}
 
 		// Close closeCh to signal any workers currently blocked waiting for tasks.
 		close(t.closeCh)
@@ -184,21 +933,158 @@ func (t *DynamicThreadPool) Stop() {
 		// Wait for all worker goroutines currently executing tasks to finish
 		t.wg.Wait()
 
-		log.Println("DynamicThreadPool: All active workers stopped.")
+		t.opts.debugf("DynamicThreadPool: All active workers stopped.\n")
 
 		// Close task channels safely after workers are done
 		close(t.priorityCh)
 		close(t.normalCh)
 
 		// Close semaphore channels
+		t.semMu.Lock()
 		close(t.prioritySem)
 		close(t.normalSem)
+		t.semMu.Unlock()
 
-		log.Println("DynamicThreadPool: Pool stopped completely.")
+		t.state.Store(int32(StateStopped))
+		deregister(t)
+		t.opts.debugf("DynamicThreadPool: Pool stopped completely.\n")
 	})
 }
 
+// drainQueues launches workers until both queues are empty. Schedule no longer
+// launches workers once the pool is stopped, so Drain has to. Normal workers
+// take priority tasks too, so a normal slot is enough for either queue.
+func (t *DynamicThreadPool) drainQueues() {
+	for len(t.priorityCh) > 0 || len(t.normalCh) > 0 {
+		t.acquireNormalSlot()
+		t.launchNormalWorker(nil)
+	}
+}
+
+// idlePollInterval is how often WaitIdle checks whether the pool is idle.
+const idlePollInterval = time.Millisecond
+
+// WaitIdle blocks until the pool is idle, without stopping it: no task is
+// queued or executing and no Schedule call is in progress. Tasks scheduled
+// during the wait simply make it wait longer. It returns nil once idle, the
+// error of ctx if ctx is done first, or ErrPoolStopped if the pool stops, as
+// tasks left queued by Stop never complete.
+func (t *DynamicThreadPool) WaitIdle(ctx context.Context) error {
+	ticker := time.NewTicker(idlePollInterval)
+	defer ticker.Stop()
+	for {
+		if t.pending.Load() == 0 {
+			return nil
+		}
+		if t.isStopped.Load() {
+			return ErrPoolStopped
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
 // GetActiveWorkers returns the current total number of worker goroutines executing tasks.
 func (t *DynamicThreadPool) GetActiveWorkers() uint32 {
 	return t.workerCount.Load()
 }
+
+// QueueDepth returns the number of tasks waiting in the priority queue if
+// urgent is set, or in the normal queue otherwise.
+func (t *DynamicThreadPool) QueueDepth(urgent bool) int {
+	if urgent {
+		return len(t.priorityCh)
+	}
+	return len(t.normalCh)
+}
+
+// CanSchedule reports whether the pool is running and the queue for urgent
+// has room for another task, so that Schedule would not wait for queue space.
+// It is advisory only: other goroutines may fill the queue or stop the pool
+// between CanSchedule and Schedule, and workers may free room at any time.
+// Use it for flow control, e.g. to pause a producer, not to guarantee that
+// Schedule will not block or reject.
+func (t *DynamicThreadPool) CanSchedule(urgent bool) bool {
+	if t.isStopped.Load() {
+		return false
+	}
+	if urgent {
+		return len(t.priorityCh) < cap(t.priorityCh)
+	}
+	return len(t.normalCh) < cap(t.normalCh)
+}
+
+// Limits returns the concurrency caps currently enforced for priority and
+// normal workers, as given by the capacity of their semaphores.
+func (t *DynamicThreadPool) Limits() (priority, normal uint32) {
+	prioritySem, normalSem, _ := t.semaphores()
+	return uint32(cap(prioritySem)), uint32(cap(normalSem))
+}
+
+// SaturationDuration returns how long every worker slot, priority and normal,
+// has been continuously occupied. It returns zero when at least one slot is
+// free. The clock restarts whenever a slot frees up.
+func (t *DynamicThreadPool) SaturationDuration() time.Duration {
+	since := t.saturatedSince.Load()
+	if since == 0 {
+		return 0
+	}
+	return time.Since(time.Unix(0, since))
+}
+
+// hasStopped implements registeredPool.
+func (t *DynamicThreadPool) hasStopped() bool {
+	return t.isStopped.Load()
+}
+
+// info implements registeredPool.
+func (t *DynamicThreadPool) info() PoolInfo {
+	return PoolInfo{
+		Name:           t.opts.name,
+		Kind:           "dynamic",
+		ActiveWorkers:  t.GetActiveWorkers(),
+		QueuedPriority: len(t.priorityCh),
+		QueuedNormal:   len(t.normalCh),
+	}
+}
+
+// Stats returns a snapshot of the pool counters. It is safe to call
+// concurrently with Schedule, but the fields are read one by one, so they may
+// be slightly out of step with each other.
+func (t *DynamicThreadPool) Stats() PoolStats {
+	return PoolStats{
+		Scheduled:          t.scheduled.Load(),
+		Completed:          t.completed.Load(),
+		Rejected:           t.rejected.Load(),
+		ActiveWorkers:      t.GetActiveWorkers(),
+		PriorityQueueDepth: len(t.priorityCh),
+		NormalQueueDepth:   len(t.normalCh),
+	}
+}
+
+// PanicCount returns how many tasks have panicked since the pool was created.
+func (t *DynamicThreadPool) PanicCount() uint64 {
+	return t.panics.count.Load()
+}
+
+// LastPanic returns the value and goroutine stack of the most recent task
+// panic, or nil and "" if no task has panicked.
+func (t *DynamicThreadPool) LastPanic() (value any, stack string) {
+	return t.panics.last()
+}
+
+// RecentErrors returns the most recent errors reported by ResultTasks, oldest
+// first. It returns nil unless the pool was created WithRecentErrors.
+func (t *DynamicThreadPool) RecentErrors() []TaskError {
+	return t.errors.snapshot()
+}
+
+// State returns the current lifecycle phase of the pool. A pool is Running from
+// construction, Draining while Stop waits for in-flight workers, and Stopped
+// once they have all exited.
+func (t *DynamicThreadPool) State() PoolState {
+	return PoolState(t.state.Load())
+}
@@ -2,9 +2,15 @@
 package thread_pool
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"os"
 	"runtime"
+	"slices"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -37,6 +43,48 @@ func (m *mockTask) Execute() {
 	// log.Printf("Task %d executed", m.id) // Optional: for debugging
 }
 
+// idTask is an IdentifiableTask that does nothing.
+type idTask struct {
+	id string
+}
+
+func (i *idTask) Execute() {}
+
+func (i *idTask) ID() string { return i.id }
+
+// blockingTask blocks in Execute until release is closed.
+type blockingTask struct {
+	started chan struct{} // Closed when Execute begins, if non-nil.
+	release chan struct{}
+	counter *atomic.Int32
+}
+
+func (b *blockingTask) Execute() {
+	if b.started != nil {
+		close(b.started)
+	}
+	<-b.release
+	if b.counter != nil {
+		b.counter.Add(1)
+	}
+}
+
+// resultTask is a task which reports err from Err after running.
+type resultTask struct {
+	err     error
+	counter *atomic.Int32
+}
+
+func (r *resultTask) Execute() {
+	if r.counter != nil {
+		r.counter.Add(1)
+	}
+}
+
+func (r *resultTask) Err() error {
+	return r.err
+}
+
 // --- Test Suite Setup ---
 
 type DynamicThreadPoolTestSuite struct {
@@ -240,6 +288,9 @@ func (suite *DynamicThreadPoolTestSuite) TestWorkerCountLifecycle() {
 	task2 := &mockTask{id: 2, counter: &counter, workTime: 50 * time.Millisecond}
 	task3 := &mockTask{id: 3, counter: &counter, workTime: 50 * time.Millisecond}
 	tp.Schedule(true, task2)
+	// Let the priority worker take task2 first, since a normal worker would
+	// otherwise prefer it over task3.
+	time.Sleep(10 * time.Millisecond)
 	tp.Schedule(false, task3)
 
 	// Wait briefly, both workers should be active
@@ -254,8 +305,1481 @@ func (suite *DynamicThreadPoolTestSuite) TestWorkerCountLifecycle() {
 	tp.Stop()
 }
 
+func (suite *DynamicThreadPoolTestSuite) TestQuietSuppressesWorkerLogs() {
+	logs := captureLogs(suite.T())
+
+	tpNil := NewDynamicThreadPool(0, 1, WithQuiet(), WithLogger(log.Default()))
+	suite.assert.Nil(tpNil)
+	suite.assert.Contains(logs.String(), "maxPriorityWorkers cannot be zero", "Error logs should survive quiet mode")
+
+	tp := NewDynamicThreadPool(2, 2, WithQuiet(), WithLogger(log.Default()))
+	suite.assert.NotNil(tp)
+	tp.Start()
+
+	var counter atomic.Int32
+	for i := 0; i < 10; i++ {
+		suite.assert.True(tp.Schedule(i%2 == 0, &mockTask{id: i, counter: &counter}))
+	}
+	suite.waitForCounter(10, &counter, 3*time.Second)
+	tp.Stop()
+
+	output := logs.String()
+	suite.assert.NotContains(output, "Launched")
+	suite.assert.NotContains(output, "worker finished")
+	suite.assert.NotContains(output, "Creating with")
+}
+
+func (suite *DynamicThreadPoolTestSuite) TestScheduleManyReportsPerItemResults() {
+	// maxNormalWorkers=1 gives a normal queue capacity of 10.
+	tp := NewDynamicThreadPool(1, 1, WithQuiet())
+	suite.assert.NotNil(tp)
+	tp.Start()
+
+	var counter atomic.Int32
+	items := make([]Task, 15)
+	for i := range items {
+		items[i] = &mockTask{id: i, counter: &counter}
+	}
+
+	results := tp.ScheduleMany(false, items)
+	suite.assert.Len(results, len(items))
+	for i, res := range results {
+		suite.assert.Same(items[i], res.Task, "Result %d should refer to its task", i)
+		if i < 10 {
+			suite.assert.True(res.Accepted, "Task %d should fit in the queue", i)
+			suite.assert.NoError(res.Err)
+		} else {
+			suite.assert.False(res.Accepted, "Task %d should overflow the queue", i)
+			suite.assert.ErrorIs(res.Err, ErrQueueFull)
+		}
+	}
+
+	suite.waitForCounter(10, &counter, 3*time.Second)
+	tp.Stop()
+	suite.assert.Equal(int32(10), counter.Load(), "Only accepted tasks should execute")
+
+	results = tp.ScheduleMany(true, items[:2])
+	for _, res := range results {
+		suite.assert.False(res.Accepted)
+		suite.assert.ErrorIs(res.Err, ErrPoolStopped)
+	}
+}
+
+func (suite *DynamicThreadPoolTestSuite) TestScheduleManyCallerRuns() {
+	// maxNormalWorkers=1 gives a normal queue capacity of 10.
+	tp := NewDynamicThreadPool(1, 1, WithQuiet(), WithRejectionPolicy(PolicyCallerRuns))
+	suite.assert.NotNil(tp)
+	tp.Start()
+	defer tp.Stop()
+
+	var counter atomic.Int32
+	items := make([]Task, 15)
+	for i := range items {
+		items[i] = &mockTask{id: i, counter: &counter}
+	}
+
+	// Workers are only launched after all items are offered, so the overflow
+	// runs on this goroutine before ScheduleMany returns.
+	results := tp.ScheduleMany(false, items)
+	suite.assert.GreaterOrEqual(counter.Load(), int32(5), "Overflowing tasks should run on the caller")
+	for i, res := range results {
+		suite.assert.True(res.Accepted, "Task %d should be accepted", i)
+		suite.assert.NoError(res.Err)
+	}
+	suite.waitForCounter(15, &counter, 3*time.Second)
+	suite.assert.Equal(uint64(0), tp.Stats().Rejected)
+}
+
+func (suite *DynamicThreadPoolTestSuite) TestScheduleManyInlineUrgent() {
+	tp := NewDynamicThreadPool(1, 1, WithQuiet(), WithInlineUrgentFallback())
+	suite.assert.NotNil(tp)
+	tp.Start()
+	defer tp.Stop()
+
+	// Occupy the only priority slot, so the urgent items run on the caller.
+	blocker := &blockingTask{started: make(chan struct{}), release: make(chan struct{})}
+	suite.assert.True(tp.Schedule(true, blocker))
+	<-blocker.started
+	defer close(blocker.release)
+
+	var counter atomic.Int32
+	results := tp.ScheduleMany(true, []Task{&mockTask{id: 1, counter: &counter}, &mockTask{id: 2, counter: &counter}})
+	for _, res := range results {
+		suite.assert.True(res.Accepted)
+		suite.assert.NoError(res.Err)
+	}
+	suite.assert.Equal(int32(2), counter.Load(), "Urgent tasks should run inline")
+}
+
+func (suite *DynamicThreadPoolTestSuite) TestExecuteOnceGuard() {
+	tp := NewDynamicThreadPool(2, 2, WithQuiet(), WithExecuteOnceGuard())
+	suite.assert.NotNil(tp)
+	tp.Start()
+
+	var counter atomic.Int32
+	task := &blockingTask{release: make(chan struct{}), counter: &counter}
+
+	suite.assert.True(tp.Schedule(false, task), "First schedule should be accepted")
+	suite.assert.False(tp.Schedule(false, task), "Same pointer should be rejected while pending")
+	suite.assert.False(tp.Schedule(true, task), "Same pointer should be rejected on the other queue too")
+
+	results := tp.ScheduleMany(false, []Task{task})
+	suite.assert.ErrorIs(results[0].Err, ErrTaskInFlight)
+
+	// A distinct task is unaffected by the guard.
+	other := &mockTask{id: 1, counter: &counter}
+	suite.assert.True(tp.Schedule(false, other))
+
+	close(task.release)
+	suite.waitForCounter(2, &counter, 3*time.Second)
+
+	// Once the first run has completed the same pointer may be scheduled again.
+	time.Sleep(10 * time.Millisecond)
+	suite.assert.True(tp.Schedule(false, task), "Pointer should be accepted again after completion")
+	suite.waitForCounter(3, &counter, 3*time.Second)
+
+	tp.Stop()
+	suite.assert.Equal(int32(3), counter.Load())
+}
+
+func (suite *DynamicThreadPoolTestSuite) TestWithoutExecuteOnceGuardRunsTwice() {
+	tp := NewDynamicThreadPool(2, 2, WithQuiet())
+	suite.assert.NotNil(tp)
+	tp.Start()
+
+	var counter atomic.Int32
+	task := &mockTask{id: 1, counter: &counter, workTime: 20 * time.Millisecond}
+	suite.assert.True(tp.Schedule(false, task))
+	suite.assert.True(tp.Schedule(false, task))
+
+	suite.waitForCounter(2, &counter, 3*time.Second)
+	tp.Stop()
+}
+
+func (suite *DynamicThreadPoolTestSuite) TestReservedPriorityWorkers() {
+	suite.assert.Nil(NewDynamicThreadPool(1, 1, WithReservedPriorityWorkers(2)),
+		"Reserved workers cannot exceed maxPriorityWorkers")
+
+	tp := NewDynamicThreadPool(2, 2, WithQuiet(), WithReservedPriorityWorkers(2))
+	suite.assert.NotNil(tp)
+	tp.Start()
+	time.Sleep(10 * time.Millisecond) // Let the reserved workers reach their select.
+	suite.assert.Equal(uint32(0), tp.GetActiveWorkers(), "Idle reserved workers are not active")
+
+	for i := 0; i < 5; i++ {
+		startedCh := make(chan time.Time, 1)
+		scheduledAt := time.Now()
+		suite.assert.True(tp.Schedule(true, FuncTask(func() { startedCh <- time.Now() })))
+
+		select {
+		case startedAt := <-startedCh:
+			suite.assert.Less(startedAt.Sub(scheduledAt), 20*time.Millisecond,
+				"Urgent task should start promptly on a reserved worker")
+		case <-time.After(time.Second):
+			suite.assert.Fail("Timeout waiting for urgent task")
+		}
+	}
+
+	// Urgent tasks beyond the reserve are still all executed.
+	var counter atomic.Int32
+	for i := 0; i < 20; i++ {
+		suite.assert.True(tp.Schedule(true, &mockTask{id: i, counter: &counter, workTime: time.Millisecond}))
+	}
+	suite.waitForCounter(20, &counter, 3*time.Second)
+
+	tp.Stop()
+	suite.assert.Equal(uint32(0), tp.GetActiveWorkers())
+}
+
+func (suite *DynamicThreadPoolTestSuite) TestLimits() {
+	tp := NewDynamicThreadPool(3, 7, WithQuiet())
+	suite.assert.NotNil(tp)
+
+	priority, normal := tp.Limits()
+	suite.assert.Equal(uint32(3), priority)
+	suite.assert.Equal(uint32(7), normal)
+}
+
+func (suite *DynamicThreadPoolTestSuite) TestScheduleBatchWithResultSuccess() {
+	tp := NewDynamicThreadPool(2, 2, WithQuiet())
+	suite.assert.NotNil(tp)
+	tp.Start()
+	defer tp.Stop()
+
+	var counter atomic.Int32
+	tasks := []Task{
+		&mockTask{id: 1, counter: &counter},
+		&resultTask{counter: &counter},
+		&mockTask{id: 3, counter: &counter, workTime: 10 * time.Millisecond},
+	}
+
+	select {
+	case err := <-tp.ScheduleBatchWithResult(tasks, false):
+		suite.assert.NoError(err)
+		suite.assert.Equal(int32(3), counter.Load(), "All tasks should have run before success is reported")
+	case <-time.After(3 * time.Second):
+		suite.assert.Fail("Timeout waiting for batch result")
+	}
+
+	select {
+	case err := <-tp.ScheduleBatchWithResult(nil, true):
+		suite.assert.NoError(err, "An empty batch succeeds immediately")
+	case <-time.After(time.Second):
+		suite.assert.Fail("Timeout waiting for empty batch result")
+	}
+}
+
+func (suite *DynamicThreadPoolTestSuite) TestScheduleBatchWithResultFailure() {
+	// A single normal worker runs the batch in order.
+	tp := NewDynamicThreadPool(1, 1, WithQuiet())
+	suite.assert.NotNil(tp)
+	tp.Start()
+
+	errBoom := errors.New("boom")
+	var counter atomic.Int32
+	tasks := []Task{
+		&resultTask{counter: &counter},
+		&resultTask{err: errBoom, counter: &counter},
+		&resultTask{counter: &counter},
+		&mockTask{id: 4, counter: &counter},
+	}
+
+	select {
+	case err := <-tp.ScheduleBatchWithResult(tasks, false):
+		suite.assert.ErrorIs(err, errBoom)
+	case <-time.After(3 * time.Second):
+		suite.assert.Fail("Timeout waiting for batch result")
+	}
+
+	tp.Stop()
+	suite.assert.Equal(int32(2), counter.Load(), "Tasks after the failure should be skipped")
+
+	select {
+	case err := <-tp.ScheduleBatchWithResult(tasks, false):
+		suite.assert.ErrorIs(err, ErrPoolStopped)
+	case <-time.After(time.Second):
+		suite.assert.Fail("Timeout waiting for batch result on stopped pool")
+	}
+}
+
+func (suite *DynamicThreadPoolTestSuite) TestScheduleBatchWithResultQueueFull() {
+	tp := NewDynamicThreadPool(1, 1, WithQuiet(), WithQueueCapacity(1, 1), WithRejectionPolicy(PolicyDropNewest))
+	suite.assert.NotNil(tp)
+	tp.Start()
+	defer tp.Stop()
+	blocker := &blockingTask{started: make(chan struct{}), release: make(chan struct{})}
+	suite.assert.True(tp.Schedule(false, blocker))
+	<-blocker.started
+	defer close(blocker.release)
+
+	// Only one of the tasks fits in the queue of the running pool.
+	var counter atomic.Int32
+	tasks := []Task{&resultTask{counter: &counter}, &resultTask{counter: &counter}}
+	select {
+	case err := <-tp.ScheduleBatchWithResult(tasks, false):
+		suite.assert.ErrorIs(err, ErrQueueFull)
+	case <-time.After(time.Second):
+		suite.assert.Fail("Timeout waiting for batch result")
+	}
+}
+
+func (suite *DynamicThreadPoolTestSuite) TestScheduleBatchWithResultRetry() {
+	tp := NewDynamicThreadPool(1, 1, WithQuiet(), WithRetry(3, time.Millisecond), WithRecentErrors(10))
+	suite.assert.NotNil(tp)
+	tp.Start()
+	defer tp.Stop()
+
+	// The failing member is retried only if it still counts towards the batch.
+	errBoom := errors.New("boom")
+	var counter atomic.Int32
+	batch := &taskBatch{result: make(chan error, 1)}
+	batch.pending.Store(2)
+	failing := &batchTask{task: &resultTask{err: errBoom, counter: &counter}, batch: batch}
+	suite.assert.True(tp.Schedule(false, failing))
+	suite.assert.ErrorIs(<-batch.result, errBoom)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	suite.assert.NoError(tp.WaitIdle(ctx))
+	suite.assert.Equal(int32(1), batch.pending.Load(), "A retried member should complete the batch once")
+	suite.assert.Len(tp.RecentErrors(), 1, "The failed member should not be retried once the batch failed")
+	suite.assert.Equal(int32(1), counter.Load())
+
+	select {
+	case err := <-tp.ScheduleBatchWithResult([]Task{&resultTask{err: errBoom}, &resultTask{}}, false):
+		suite.assert.ErrorIs(err, errBoom)
+	case <-time.After(time.Second):
+		suite.assert.Fail("Timeout waiting for batch result")
+	}
+}
+
+func (suite *DynamicThreadPoolTestSuite) TestScheduleBatch() {
+	var counter atomic.Int32
+	tp := NewDynamicThreadPool(1, 1, WithQuiet(), WithQueueCapacity(1, 2), WithRejectionPolicy(PolicyDropNewest))
+	suite.assert.NotNil(tp)
+	tp.Start()
+	defer tp.Stop()
+	blocker := &blockingTask{started: make(chan struct{}), release: make(chan struct{}), counter: &counter}
+	suite.assert.True(tp.Schedule(false, blocker))
+	<-blocker.started
+
+	// Only two of the four tasks fit in the queue.
+	batch := []Task{
+		&mockTask{id: 1, counter: &counter},
+		&mockTask{id: 2, counter: &counter},
+		&mockTask{id: 3, counter: &counter},
+		&mockTask{id: 4, counter: &counter},
+	}
+	accepted, ok := tp.ScheduleBatch(false, batch)
+	suite.assert.Equal(2, accepted)
+	suite.assert.False(ok)
+	suite.assert.Equal(uint64(1), tp.Stats().Rejected, "The batch stops at the first rejection")
+
+	close(blocker.release)
+	suite.waitForCounter(3, &counter, time.Second)
+
+	accepted, ok = tp.ScheduleBatch(false, batch[2:])
+	suite.assert.Equal(2, accepted)
+	suite.assert.True(ok)
+	suite.waitForCounter(5, &counter, time.Second)
+
+	accepted, ok = tp.ScheduleBatch(true, nil)
+	suite.assert.Equal(0, accepted)
+	suite.assert.True(ok)
+}
+
+func (suite *DynamicThreadPoolTestSuite) TestCanSchedule() {
+	var counter atomic.Int32
+	tp := NewDynamicThreadPool(1, 1, WithQuiet(), WithQueueCapacity(1, 2), WithRejectionPolicy(PolicyDropNewest))
+	suite.assert.NotNil(tp)
+	tp.Start()
+	blocker := &blockingTask{started: make(chan struct{}), release: make(chan struct{}), counter: &counter}
+	suite.assert.True(tp.Schedule(false, blocker))
+	<-blocker.started
+
+	suite.assert.True(tp.CanSchedule(false))
+	suite.assert.Equal(0, tp.QueueDepth(false))
+	suite.assert.True(tp.Schedule(false, &mockTask{id: 1, counter: &counter}))
+	suite.assert.True(tp.Schedule(false, &mockTask{id: 2, counter: &counter}))
+	suite.assert.Equal(2, tp.QueueDepth(false))
+	suite.assert.False(tp.CanSchedule(false), "The normal queue is full")
+	suite.assert.True(tp.CanSchedule(true), "The priority queue still has room")
+	suite.assert.Equal(0, tp.QueueDepth(true))
+
+	close(blocker.release)
+	suite.waitForCounter(3, &counter, time.Second)
+	suite.assert.True(tp.CanSchedule(false))
+	suite.assert.Equal(0, tp.QueueDepth(false))
+
+	tp.Stop()
+	suite.assert.False(tp.CanSchedule(true), "A stopped pool accepts nothing")
+}
+
+func (suite *DynamicThreadPoolTestSuite) TestRateLimit() {
+	const rate = 20 // One task every 50ms.
+	tp := NewDynamicThreadPool(5, 5, WithQuiet(), WithRateLimit(rate))
+	suite.assert.NotNil(tp)
+	tp.Start()
+
+	var mu sync.Mutex
+	var completions []time.Time
+	var counter atomic.Int32
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		suite.assert.True(tp.Schedule(i%2 == 0, FuncTask(func() {
+			mu.Lock()
+			completions = append(completions, time.Now())
+			mu.Unlock()
+			counter.Add(1)
+		})))
+	}
+	suite.waitForCounter(10, &counter, 3*time.Second)
+	tp.Stop()
+
+	// The first task uses the initial token; each of the other nine waits 50ms.
+	suite.assert.GreaterOrEqual(time.Since(start), 400*time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	for i := 1; i < len(completions); i++ {
+		// Allow for timer jitter, but no two tasks may start back to back.
+		suite.assert.Greater(completions[i].Sub(completions[i-1]), 30*time.Millisecond,
+			"Task %d started too soon after the previous one", i)
+	}
+}
+
+func (suite *DynamicThreadPoolTestSuite) TestRateLimitBurst() {
+	tp := NewDynamicThreadPool(5, 5, WithQuiet(), WithRateLimit(1), WithRateBurst(5))
+	suite.assert.NotNil(tp)
+	tp.Start()
+	defer tp.Stop()
+
+	var counter atomic.Int32
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		suite.assert.True(tp.Schedule(false, &mockTask{id: i, counter: &counter}))
+	}
+	suite.waitForCounter(5, &counter, time.Second)
+	suite.assert.Less(time.Since(start), 500*time.Millisecond, "A full bucket should let the burst through at once")
+}
+
+func (suite *DynamicThreadPoolTestSuite) TestSaturationDuration() {
+	tp := NewDynamicThreadPool(1, 1, WithQuiet())
+	suite.assert.NotNil(tp)
+	tp.Start()
+	defer tp.Stop()
+
+	suite.assert.Equal(time.Duration(0), tp.SaturationDuration(), "Idle pool is not saturated")
+
+	urgent := &blockingTask{started: make(chan struct{}), release: make(chan struct{})}
+	normal := &blockingTask{started: make(chan struct{}), release: make(chan struct{})}
+	suite.assert.True(tp.Schedule(true, urgent))
+	// Wait for the priority worker to take it, or the normal worker might.
+	<-urgent.started
+	suite.assert.Equal(time.Duration(0), tp.SaturationDuration(), "A free normal slot means not saturated")
+	suite.assert.True(tp.Schedule(false, normal))
+	<-normal.started
+
+	const interval = 100 * time.Millisecond
+	time.Sleep(interval)
+	saturation := tp.SaturationDuration()
+	suite.assert.GreaterOrEqual(saturation, interval)
+	suite.assert.Less(saturation, 2*interval)
+
+	close(normal.release)
+	for i := 0; i < 100 && tp.SaturationDuration() != 0; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	suite.assert.Equal(time.Duration(0), tp.SaturationDuration(), "Freeing a slot should reset the clock")
+	close(urgent.release)
+}
+
+func (suite *DynamicThreadPoolTestSuite) TestInlineUrgentFallback() {
+	tp := NewDynamicThreadPool(1, 1, WithQuiet(), WithInlineUrgentFallback())
+	suite.assert.NotNil(tp)
+	tp.Start()
+	defer tp.Stop()
+
+	// With a free slot the urgent task goes to a worker as usual.
+	var counter atomic.Int32
+	suite.assert.True(tp.Schedule(true, &mockTask{id: 1, counter: &counter}))
+	suite.waitForCounter(1, &counter, time.Second)
+	time.Sleep(10 * time.Millisecond) // Let the worker release its slot.
+
+	// Occupy the only priority slot.
+	blocker := &blockingTask{started: make(chan struct{}), release: make(chan struct{})}
+	suite.assert.True(tp.Schedule(true, blocker))
+	<-blocker.started
+
+	// The task is not synchronised: it must run on this goroutine, before
+	// Schedule returns, for the assertion below to hold.
+	ranInline := false
+	suite.assert.True(tp.Schedule(true, FuncTask(func() { ranInline = true })))
+	suite.assert.True(ranInline, "Urgent task should run inline when no priority slot is free")
+	suite.assert.Equal(uint32(1), tp.GetActiveWorkers(), "No extra worker should be launched")
+
+	close(blocker.release)
+}
+
+func (suite *DynamicThreadPoolTestSuite) TestStateTransitions() {
+	tp := NewDynamicThreadPool(1, 1, WithQuiet())
+	suite.assert.NotNil(tp)
+	suite.assert.Equal(StateRunning, tp.State())
+	tp.Start()
+	suite.assert.Equal(StateRunning, tp.State())
+
+	blocker := &blockingTask{started: make(chan struct{}), release: make(chan struct{})}
+	suite.assert.True(tp.Schedule(false, blocker))
+	<-blocker.started
+
+	stopped := make(chan struct{})
+	go func() {
+		tp.Stop()
+		close(stopped)
+	}()
+
+	// Stop waits for the in-flight task, so the pool sits in Draining.
+	suite.waitForState(tp, StateDraining, time.Second)
+	suite.assert.False(tp.Schedule(false, &mockTask{id: 1}), "Draining pool should reject tasks")
+
+	close(blocker.release)
+	<-stopped
+	suite.assert.Equal(StateStopped, tp.State())
+	suite.assert.Equal("Stopped", tp.State().String())
+}
+
+func (suite *DynamicThreadPoolTestSuite) TestRecentErrors() {
+	tp := NewDynamicThreadPool(1, 1, WithQuiet(), WithRecentErrors(3))
+	suite.assert.NotNil(tp)
+	tp.Start()
+	defer tp.Stop()
+
+	suite.assert.Empty(tp.RecentErrors(), "No errors should be recorded initially")
+
+	// Run the tasks one at a time so the errors are recorded in order.
+	var errs []error
+	for i := 1; i <= 5; i++ {
+		err := fmt.Errorf("failure %d", i)
+		errs = append(errs, err)
+		suite.assert.True(tp.Schedule(false, &resultTask{err: err}))
+		want := min(i, 3)
+		suite.assert.Eventually(func() bool { return len(tp.RecentErrors()) == want && tp.RecentErrors()[want-1].Err == err },
+			time.Second, 5*time.Millisecond, "Error %d should be recorded", i)
+	}
+	// Successful tasks are not recorded.
+	var counter atomic.Int32
+	suite.assert.True(tp.Schedule(false, &resultTask{counter: &counter}))
+	suite.waitForCounter(1, &counter, time.Second)
+
+	recent := tp.RecentErrors()
+	suite.assert.Len(recent, 3, "Only the last 3 errors should be kept")
+	for i, te := range recent {
+		suite.assert.Equal(errs[i+2], te.Err, "Errors should be the most recent ones, oldest first")
+		suite.assert.Equal("*thread_pool.resultTask", te.Label)
+		suite.assert.False(te.Time.IsZero())
+	}
+}
+
+func (suite *DynamicThreadPoolTestSuite) TestMigrateFromStaticPool() {
+	src := NewStaticThreadPool(1, WithQuiet())
+	suite.assert.NotNil(src)
+	src.Start()
+
+	// Keep the only static worker busy so the remaining tasks stay queued.
+	gate := &blockingTask{started: make(chan struct{}), release: make(chan struct{})}
+	src.Schedule(false, gate)
+	<-gate.started
+
+	// The priority queue of a one-worker static pool holds two tasks.
+	counters := make([]atomic.Int32, 8)
+	for i := range counters {
+		src.Schedule(i%4 == 0, &resultTask{counter: &counters[i]})
+	}
+
+	dstCh := make(chan *DynamicThreadPool, 1)
+	go func() {
+		dst, rejected := MigrateToDynamic(src, 2, 2, WithQuiet())
+		suite.assert.Empty(rejected)
+		dstCh <- dst
+	}()
+	// The migration waits for the running gate task before moving the queue.
+	time.Sleep(20 * time.Millisecond)
+	close(gate.release)
+
+	var dst *DynamicThreadPool
+	select {
+	case dst = <-dstCh:
+	case <-time.After(3 * time.Second):
+		suite.assert.Fail("Timeout waiting for migration")
+		return
+	}
+	suite.assert.NotNil(dst)
+	defer dst.Stop()
+
+	for i := range counters {
+		suite.waitForCounter(1, &counters[i], 2*time.Second)
+	}
+	time.Sleep(20 * time.Millisecond)
+	for i := range counters {
+		suite.assert.Equal(int32(1), counters[i].Load(), "Task %d should run exactly once", i)
+	}
+}
+
+func (suite *DynamicThreadPoolTestSuite) TestMigrateMovesPinnedTasks() {
+	src := NewStaticThreadPool(2, WithQuiet(), WithWorkerAffinity())
+	suite.assert.NotNil(src)
+	src.Start()
+
+	// Tasks pinned to a busy worker wait in its own channel.
+	gate := &blockingTask{started: make(chan struct{}), release: make(chan struct{})}
+	suite.assert.True(src.ScheduleToWorker(0, gate))
+	<-gate.started
+	counters := make([]atomic.Int32, 5)
+	for i := range counters {
+		suite.assert.True(src.ScheduleToWorker(0, &resultTask{counter: &counters[i]}))
+	}
+
+	dstCh := make(chan *DynamicThreadPool, 1)
+	go func() {
+		dst, rejected := MigrateToDynamic(src, 2, 2, WithQuiet())
+		suite.assert.Empty(rejected)
+		dstCh <- dst
+	}()
+	time.Sleep(20 * time.Millisecond)
+	close(gate.release)
+
+	dst := <-dstCh
+	suite.assert.NotNil(dst)
+	defer dst.Stop()
+	for i := range counters {
+		suite.waitForCounter(1, &counters[i], 2*time.Second)
+	}
+	time.Sleep(20 * time.Millisecond)
+	for i := range counters {
+		suite.assert.Equal(int32(1), counters[i].Load(), "Pinned task %d should run exactly once", i)
+	}
+}
+
+func (suite *DynamicThreadPoolTestSuite) TestMigrateReturnsRejectedTasks() {
+	src := NewStaticThreadPool(1, WithQuiet())
+	suite.assert.NotNil(src)
+	src.Start()
+
+	gate := &blockingTask{started: make(chan struct{}), release: make(chan struct{})}
+	src.Schedule(false, gate)
+	<-gate.started
+
+	// While stopping, the source worker picks each next task over its close
+	// signal only half the time, so it leaves most of them. The moved ones
+	// block, and the destination holds at most one running and one queued
+	// normal task, so it rejects the rest.
+	const tasks = 20
+	var counter atomic.Int32
+	release := make(chan struct{})
+	for i := 0; i < tasks; i++ {
+		src.ScheduleFunc(false, func() {
+			if src.GetRunningWorkers() == 0 {
+				<-release
+			}
+			counter.Add(1)
+		})
+	}
+	type migration struct {
+		dst      *DynamicThreadPool
+		rejected []Task
+	}
+	done := make(chan migration, 1)
+	go func() {
+		dst, rejected := MigrateToDynamic(src, 1, 1, WithQuiet(), WithQueueCapacity(1, 1), WithRejectionPolicy(PolicyDropNewest))
+		done <- migration{dst, rejected}
+	}()
+	time.Sleep(20 * time.Millisecond)
+	close(gate.release)
+	m := <-done
+	suite.assert.NotNil(m.dst)
+	defer m.dst.Stop()
+	rejected := m.rejected
+	suite.assert.NotEmpty(rejected)
+
+	close(release)
+	for _, item := range rejected {
+		item.Execute()
+	}
+	suite.waitForCounter(tasks, &counter, 2*time.Second)
+}
+
+func (suite *DynamicThreadPoolTestSuite) TestMigrateToStaticReturnsRejectedTasks() {
+	// Without PolicyBlock, scheduling on the busy pool does not wait for a worker.
+	src := NewDynamicThreadPool(1, 1, WithQuiet(), WithRejectionPolicy(PolicyDropNewest))
+	suite.assert.NotNil(src)
+	src.Start()
+	gate := &blockingTask{started: make(chan struct{}), release: make(chan struct{})}
+	suite.assert.True(src.Schedule(false, gate))
+	<-gate.started
+
+	// The static pool holds at most one running and one queued task, as the
+	// moved tasks block, so it rejects the rest.
+	const tasks = 5
+	var counter atomic.Int32
+	release := make(chan struct{})
+	submitted := make(map[Task]*TaskFuture)
+	for i := 0; i < tasks; i++ {
+		task := &blockingTask{release: release, counter: &counter}
+		submitted[task] = src.ScheduleWithFuture(false, task)
+	}
+
+	type migration struct {
+		dst      *StaticThreadPool
+		rejected []Task
+	}
+	done := make(chan migration, 1)
+	go func() {
+		dst, rejected := MigrateToStatic(src, 1, WithQuiet(), WithQueueCapacity(1, 1), WithRejectionPolicy(PolicyDropNewest))
+		done <- migration{dst, rejected}
+	}()
+	time.Sleep(20 * time.Millisecond)
+	close(gate.release)
+	m := <-done
+	suite.assert.NotNil(m.dst)
+	suite.assert.GreaterOrEqual(len(m.rejected), tasks-2)
+
+	for _, item := range m.rejected {
+		future, ok := submitted[item]
+		suite.assert.True(ok, "Rejected task %T should be one of the tasks scheduled on src", item)
+		suite.assert.IsType(&blockingTask{}, item)
+		suite.assert.ErrorIs(future.Wait(), ErrQueueFull)
+	}
+
+	close(release)
+	for _, item := range m.rejected {
+		item.Execute()
+	}
+	suite.waitForCounter(tasks, &counter, 2*time.Second)
+	m.dst.Stop()
+}
+
+func (suite *DynamicThreadPoolTestSuite) TestListPools() {
+	// Other tests may leave pools behind, so only look at the ones named here.
+	named := func() map[string]PoolInfo {
+		found := make(map[string]PoolInfo)
+		for _, info := range ListPools() {
+			if info.Name == "registry-static" || info.Name == "registry-dynamic" {
+				found[info.Name] = info
+			}
+		}
+		return found
+	}
+
+	static := NewStaticThreadPool(2, WithQuiet(), WithName("registry-static"))
+	suite.assert.NotNil(static)
+	static.Start()
+	dynamic := NewDynamicThreadPool(1, 1, WithQuiet(), WithName("registry-dynamic"))
+	suite.assert.NotNil(dynamic)
+	dynamic.Start()
+
+	pools := named()
+	suite.assert.Len(pools, 2, "Both pools should be listed")
+	suite.assert.Equal("static", pools["registry-static"].Kind)
+	suite.assert.Equal("dynamic", pools["registry-dynamic"].Kind)
+
+	static.Stop()
+	pools = named()
+	suite.assert.Len(pools, 1, "Stopped pool should be removed")
+	suite.assert.Contains(pools, "registry-dynamic")
+
+	dynamic.Stop()
+	suite.assert.Empty(named())
+}
+
+func (suite *DynamicThreadPoolTestSuite) TestListPoolsNeverStarted() {
+	listed := func(name string) bool {
+		for _, info := range ListPools() {
+			if info.Name == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	pools := map[string]Pool{
+		"unstarted-static":  NewStaticThreadPool(1, WithQuiet(), WithName("unstarted-static")),
+		"unstarted-dynamic": NewDynamicThreadPool(1, 1, WithQuiet(), WithName("unstarted-dynamic")),
+		"unstarted-tiered":  NewTieredThreadPool([]uint32{1}, WithQuiet(), WithName("unstarted-tiered")),
+		"unstarted-heap":    NewHeapThreadPool(1, WithQuiet(), WithName("unstarted-heap")),
+	}
+	for name, pool := range pools {
+		suite.assert.NotNil(pool)
+		suite.assert.False(listed(name), "%s: a pool that was never started should not be listed", name)
+
+		pool.Stop()
+		pool.Start()
+		suite.assert.False(listed(name), "%s: starting a stopped pool should not list it", name)
+	}
+}
+
+func (suite *DynamicThreadPoolTestSuite) TestScheduleWithFuture() {
+	tp := NewDynamicThreadPool(2, 2, WithQuiet())
+	suite.assert.NotNil(tp)
+	tp.Start()
+	defer tp.Stop()
+
+	slow := &blockingTask{release: make(chan struct{})}
+	slowFuture := tp.ScheduleWithFuture(false, slow)
+	var counter atomic.Int32
+	fastFuture := tp.ScheduleWithFuture(true, &mockTask{id: 1, counter: &counter, workTime: 10 * time.Millisecond})
+
+	// Waiting on one task does not depend on the others.
+	suite.assert.NoError(fastFuture.Wait())
+	suite.assert.Equal(int32(1), counter.Load(), "Wait should return after Execute")
+	select {
+	case <-slowFuture.Done():
+		suite.assert.Fail("Future should not complete before its task")
+	default:
+	}
+	suite.assert.NoError(slowFuture.Err(), "Err should be nil while pending")
+
+	// Several goroutines may wait on the same future.
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			suite.assert.NoError(slowFuture.Wait())
+		}()
+	}
+	close(slow.release)
+	wg.Wait()
+	suite.assert.NoError(slowFuture.Err())
+}
+
+func (suite *DynamicThreadPoolTestSuite) TestScheduleWithFutureRejected() {
+	tp := NewDynamicThreadPool(1, 1, WithQuiet())
+	suite.assert.NotNil(tp)
+	tp.Start()
+	tp.Stop()
+
+	future := tp.ScheduleWithFuture(false, &mockTask{id: 1})
+	select {
+	case <-future.Done():
+	default:
+		suite.assert.Fail("Future of a rejected task should already be complete")
+	}
+	suite.assert.ErrorIs(future.Err(), ErrPoolStopped)
+	suite.assert.ErrorIs(future.Wait(), ErrPoolStopped)
+
+	var nilFuture *TaskFuture
+	suite.assert.NoError(nilFuture.Err(), "Err should be nil-safe")
+}
+
+func (suite *DynamicThreadPoolTestSuite) TestScheduleCtxSkipsCancelledTasks() {
+	tp := NewDynamicThreadPool(1, 1, WithQuiet())
+	suite.assert.NotNil(tp)
+	tp.Start()
+	defer tp.Stop()
+
+	// Occupy the only normal worker so the next tasks stay queued.
+	blocker := &blockingTask{started: make(chan struct{}), release: make(chan struct{})}
+	suite.assert.True(tp.Schedule(false, blocker))
+	<-blocker.started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var cancelled, live atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Blocks waiting for a worker slot once queued.
+			suite.assert.True(tp.ScheduleCtx(ctx, false, &mockTask{id: i, counter: &cancelled}))
+		}()
+	}
+	suite.assert.Eventually(func() bool { return len(tp.normalCh) == 3 }, time.Second, 5*time.Millisecond)
+
+	cancel()
+	close(blocker.release)
+	wg.Wait()
+
+	suite.assert.True(tp.Schedule(false, &mockTask{id: 4, counter: &live}))
+	suite.waitForCounter(1, &live, time.Second)
+	suite.assert.Equal(int32(0), cancelled.Load(), "Tasks whose context was cancelled should not execute")
+
+	suite.assert.False(tp.ScheduleCtx(ctx, false, &mockTask{id: 5}), "A done context should be rejected")
+}
+
+func (suite *DynamicThreadPoolTestSuite) TestRecoverFromPanic() {
+	var panics atomic.Int32
+	tp := NewDynamicThreadPool(1, 1, WithQuiet(), WithLogger(log.Default()), WithPanicHandler(func(recovered any) {
+		panics.Add(1)
+	}))
+	suite.assert.NotNil(tp)
+	tp.Start()
+	defer tp.Stop()
+	logs := captureLogs(suite.T())
+
+	future := tp.ScheduleWithFuture(false, &mockTask{id: 1, panicOnExec: true})
+	suite.assert.ErrorIs(future.Wait(), ErrTaskPanicked)
+	suite.assert.Contains(future.Err().Error(), "mockTask 1 panicking")
+	suite.assert.True(tp.Schedule(true, &mockTask{id: 2, panicOnExec: true}))
+	suite.assert.Eventually(func() bool { return panics.Load() == 2 }, time.Second, 5*time.Millisecond)
+	suite.assert.Contains(logs.String(), "Recovered panic in task *thread_pool.mockTask")
+
+	// The worker slots were released, so the pool keeps running tasks.
+	var counter atomic.Int32
+	suite.assert.True(tp.Schedule(false, &mockTask{id: 3, counter: &counter}))
+	suite.assert.True(tp.Schedule(true, &mockTask{id: 4, counter: &counter}))
+	suite.waitForCounter(2, &counter, time.Second)
+	suite.assert.Eventually(func() bool { return tp.GetActiveWorkers() == 0 }, time.Second, 5*time.Millisecond)
+}
+
+func (suite *DynamicThreadPoolTestSuite) TestPanicStats() {
+	tp := NewDynamicThreadPool(1, 2, WithQuiet())
+	suite.assert.NotNil(tp)
+	tp.Start()
+	defer tp.Stop()
+
+	value, stack := tp.LastPanic()
+	suite.assert.Nil(value)
+	suite.assert.Empty(stack)
+
+	var futures []*TaskFuture
+	for i := 0; i < 4; i++ {
+		futures = append(futures, tp.ScheduleWithFuture(i%2 == 0, &mockTask{id: i, panicOnExec: true}))
+	}
+	for _, future := range futures {
+		suite.assert.ErrorIs(future.Wait(), ErrTaskPanicked)
+	}
+	suite.assert.Equal(uint64(4), tp.PanicCount())
+
+	value, stack = tp.LastPanic()
+	suite.assert.Contains(fmt.Sprint(value), "panicking")
+	suite.assert.Contains(stack, "mockTask")
+}
+
+func (suite *DynamicThreadPoolTestSuite) TestNormalWorkersDrainPriorityQueue() {
+	tp := NewDynamicThreadPool(1, 10, WithQuiet())
+	suite.assert.NotNil(tp)
+	tp.Start()
+	defer tp.Stop()
+
+	// A single priority worker would need a second for these; with the ten
+	// normal workers helping it takes about a tenth of that.
+	const tasks = 50
+	const workTime = 20 * time.Millisecond
+	var counter, running, maxRunning atomic.Int32
+	start := time.Now()
+	for i := 0; i < tasks; i++ {
+		suite.assert.True(tp.Schedule(true, FuncTask(func() {
+			n := running.Add(1)
+			for m := maxRunning.Load(); n > m && !maxRunning.CompareAndSwap(m, n); m = maxRunning.Load() {
+			}
+			time.Sleep(workTime)
+			running.Add(-1)
+			counter.Add(1)
+		})))
+	}
+	suite.waitForCounter(tasks, &counter, 2*time.Second)
+	suite.assert.Less(time.Since(start), tasks*workTime/2, "Idle normal workers should help with priority tasks")
+	suite.assert.LessOrEqual(maxRunning.Load(), int32(11), "Workers must stay within maxPriorityWorkers+maxNormalWorkers")
+	suite.assert.Eventually(func() bool { return tp.GetActiveWorkers() == 0 }, time.Second, 5*time.Millisecond)
+}
+
+func (suite *DynamicThreadPoolTestSuite) TestReusableWorkers() {
+	suite.assert.Nil(NewDynamicThreadPoolReusable(1, 1, 0), "keepAlive must be positive")
+
+	const keepAlive = 100 * time.Millisecond
+	tp := NewDynamicThreadPoolReusable(1, 2, keepAlive, WithLogger(log.Default()))
+	suite.assert.NotNil(tp)
+	tp.Start()
+	defer tp.Stop()
+	logs := captureLogs(suite.T())
+
+	// Tasks arriving within keepAlive of each other reuse the same worker.
+	var counter atomic.Int32
+	for i := 1; i <= 5; i++ {
+		suite.assert.True(tp.Schedule(false, &mockTask{id: i, counter: &counter}))
+		suite.waitForCounter(int32(i), &counter, time.Second)
+		time.Sleep(keepAlive / 10)
+	}
+	suite.assert.Equal(1, strings.Count(logs.String(), "Launched normal worker"), "Worker should be reused")
+
+	// The idle worker is not active, but keeps its slot until keepAlive passes.
+	suite.assert.Equal(uint32(0), tp.GetActiveWorkers(), "Idle worker should not count as active")
+	suite.assert.Equal(1, len(tp.normalSem), "Idle worker should keep its slot")
+	suite.assert.Eventually(func() bool { return len(tp.normalSem) == 0 }, 3*keepAlive, 5*time.Millisecond,
+		"Worker should exit after keepAlive")
+	suite.assert.Equal(1, strings.Count(logs.String(), "Normal worker finished"))
+
+	// Once all slots are held, queued tasks are picked up by the running workers.
+	blockers := []*blockingTask{
+		{started: make(chan struct{}), release: make(chan struct{})},
+		{started: make(chan struct{}), release: make(chan struct{})},
+	}
+	for _, b := range blockers {
+		suite.assert.True(tp.Schedule(false, b))
+		<-b.started
+	}
+	for i := 0; i < 10; i++ {
+		suite.assert.True(tp.Schedule(false, &mockTask{id: i, counter: &counter}), "Schedule should not block")
+	}
+	for _, b := range blockers {
+		close(b.release)
+	}
+	suite.waitForCounter(15, &counter, time.Second)
+}
+
+func (suite *DynamicThreadPoolTestSuite) TestStats() {
+	// Reusable workers let Schedule return while every slot is busy.
+	tp := NewDynamicThreadPoolReusable(1, 1, 10*time.Millisecond, WithQuiet())
+	suite.assert.NotNil(tp)
+	tp.Start()
+
+	suite.assert.Equal(PoolStats{}, tp.Stats(), "A new pool has no activity")
+
+	// Hold the only normal worker so the next tasks stay queued.
+	blocker := &blockingTask{started: make(chan struct{}), release: make(chan struct{})}
+	suite.assert.True(tp.Schedule(false, blocker))
+	<-blocker.started
+	var counter atomic.Int32
+	results := tp.ScheduleMany(false, []Task{&mockTask{id: 1, counter: &counter}, &mockTask{id: 2, counter: &counter}})
+	suite.assert.True(results[0].Accepted && results[1].Accepted)
+
+	stats := tp.Stats()
+	suite.assert.Equal(uint64(3), stats.Scheduled)
+	suite.assert.Equal(uint64(0), stats.Completed)
+	suite.assert.Equal(uint32(1), stats.ActiveWorkers)
+	suite.assert.Equal(2, stats.NormalQueueDepth)
+	suite.assert.Equal(0, stats.PriorityQueueDepth)
+
+	close(blocker.release)
+	suite.waitForCounter(2, &counter, time.Second)
+	suite.assert.Eventually(func() bool { return tp.Stats().Completed == 3 }, time.Second, 5*time.Millisecond)
+
+	tp.Stop()
+	suite.assert.False(tp.Schedule(true, &mockTask{id: 3}))
+	stats = tp.Stats()
+	suite.assert.Equal(uint64(3), stats.Scheduled)
+	suite.assert.Equal(uint64(1), stats.Rejected)
+	suite.assert.Equal(0, stats.NormalQueueDepth)
+}
+
+func (suite *DynamicThreadPoolTestSuite) TestRejectionPolicies() {
+	// fill occupies the only normal worker and the single queue slot of a
+	// fresh pool created with policy, returning the blocked task.
+	fill := func(policy RejectionPolicy, counter *atomic.Int32) (*DynamicThreadPool, *blockingTask) {
+		tp := NewDynamicThreadPool(1, 1, WithQuiet(), WithQueueCapacity(1, 1), WithRejectionPolicy(policy))
+		suite.assert.NotNil(tp)
+		tp.Start()
+		blocker := &blockingTask{started: make(chan struct{}), release: make(chan struct{}), counter: counter}
+		suite.assert.True(tp.Schedule(false, blocker))
+		<-blocker.started
+		suite.assert.True(tp.Schedule(false, &mockTask{id: 1, counter: counter}))
+		suite.assert.Equal(1, tp.Stats().NormalQueueDepth)
+		return tp, blocker
+	}
+
+	suite.Run("DropNewest", func() {
+		var counter atomic.Int32
+		tp, blocker := fill(PolicyDropNewest, &counter)
+		defer tp.Stop()
+
+		suite.assert.ErrorIs(tp.TrySchedule(false, &mockTask{id: 2, counter: &counter}), ErrQueueFull)
+		suite.assert.False(tp.Schedule(false, &mockTask{id: 3, counter: &counter}))
+		suite.assert.Equal(uint64(2), tp.Stats().Rejected)
+
+		close(blocker.release)
+		suite.waitForCounter(2, &counter, time.Second)
+		time.Sleep(50 * time.Millisecond)
+		suite.assert.Equal(int32(2), counter.Load(), "Dropped tasks must not run")
+	})
+
+	suite.Run("CallerRuns", func() {
+		var counter atomic.Int32
+		tp, blocker := fill(PolicyCallerRuns, &counter)
+		defer tp.Stop()
+
+		suite.assert.True(tp.Schedule(false, &mockTask{id: 2, counter: &counter}))
+		suite.assert.Equal(int32(1), counter.Load(), "The overflow task runs before Schedule returns")
+
+		close(blocker.release)
+		suite.waitForCounter(3, &counter, time.Second)
+	})
+
+	suite.Run("Block", func() {
+		var counter atomic.Int32
+		tp := NewDynamicThreadPool(1, 1, WithQuiet(), WithQueueCapacity(1, 1))
+		suite.assert.NotNil(tp)
+		tp.Start()
+		defer tp.Stop()
+		blocker := &blockingTask{started: make(chan struct{}), release: make(chan struct{}), counter: &counter}
+		suite.assert.True(tp.Schedule(false, blocker))
+		<-blocker.started
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			suite.assert.True(tp.Schedule(false, &mockTask{id: 1, counter: &counter}))
+			suite.assert.True(tp.Schedule(false, &mockTask{id: 2, counter: &counter}))
+		}()
+		select {
+		case <-done:
+			suite.T().Fatal("Schedule should block while the pool is full")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		close(blocker.release)
+		<-done
+		suite.waitForCounter(3, &counter, time.Second)
+		suite.assert.Equal(uint64(0), tp.Stats().Rejected)
+	})
+}
+
+func (suite *DynamicThreadPoolTestSuite) TestStopContext() {
+	tp := NewDynamicThreadPool(1, 1, WithQuiet())
+	suite.assert.NotNil(tp)
+	tp.Start()
+
+	const sleep = 200 * time.Millisecond
+	started := make(chan struct{})
+	suite.assert.True(tp.Schedule(false, FuncTask(func() {
+		close(started)
+		time.Sleep(sleep)
+	})))
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	begin := time.Now()
+	suite.assert.ErrorIs(tp.StopContext(ctx), context.DeadlineExceeded)
+	suite.assert.Less(time.Since(begin), sleep, "StopContext should not wait for the hung task")
+	suite.assert.Equal(StateDraining, tp.State())
+	suite.assert.False(tp.Schedule(false, FuncTask(func() {})), "The pool should be marked stopped")
+
+	// The shutdown finishes in the background once the task returns.
+	suite.waitForState(tp, StateStopped, time.Second)
+	suite.assert.Equal(uint32(0), tp.GetActiveWorkers())
+	suite.assert.NoError(tp.StopContext(context.Background()))
+}
+
+func (suite *DynamicThreadPoolTestSuite) TestSubmit() {
+	var counter atomic.Int32
+	tp := NewDynamicThreadPool(1, 1, WithQuiet(), WithRejectionPolicy(PolicyDropNewest))
+	suite.assert.NotNil(tp)
+	tp.Start()
+
+	// Submit returns once the task has started.
+	release := make(chan struct{})
+	blocker := &blockingTask{started: make(chan struct{}), release: release, counter: &counter}
+	suite.assert.NoError(tp.Submit(false, blocker))
+	suite.assert.Equal(uint32(1), tp.GetActiveWorkers(), "A worker should have taken the task")
+	<-blocker.started
+
+	close(release)
+	suite.assert.NoError(tp.Submit(true, &mockTask{id: 1, counter: &counter}))
+	suite.waitForCounter(2, &counter, time.Second)
+	tp.Stop()
+
+	begin := time.Now()
+	suite.assert.ErrorIs(tp.Submit(true, &mockTask{id: 2, counter: &counter}), ErrPoolStopped)
+	suite.assert.Less(time.Since(begin), 50*time.Millisecond, "A stopped pool fails fast")
+	suite.assert.Equal(int32(2), counter.Load())
+}
+
+func (suite *DynamicThreadPoolTestSuite) TestDrain() {
+	// Reusable workers let Schedule return while every slot is busy, so tasks
+	// are still queued when Drain is called.
+	tp := NewDynamicThreadPoolReusable(2, 2, 10*time.Millisecond, WithQuiet(), WithQueueCapacity(100, 100))
+	suite.assert.NotNil(tp)
+	tp.Start()
+
+	var counter atomic.Int32
+	for i := 0; i < 100; i++ {
+		suite.assert.True(tp.Schedule(i%5 == 0, &mockTask{id: i, counter: &counter, workTime: time.Millisecond}))
+	}
+	suite.assert.Greater(tp.Stats().NormalQueueDepth, 0, "Tasks should still be queued")
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		time.Sleep(5 * time.Millisecond)
+		tp.Stop() // Must wait for the drain rather than cut it short.
+	}()
+	tp.Drain()
+	suite.assert.Equal(int32(100), counter.Load(), "Drain should execute every queued task")
+	suite.assert.Equal(StateStopped, tp.State())
+	<-stopped
+
+	tp.Drain() // Idempotent.
+	suite.assert.False(tp.Schedule(false, &mockTask{id: 100, counter: &counter}))
+	suite.assert.Equal(int32(100), counter.Load())
+}
+
+func (suite *DynamicThreadPoolTestSuite) TestScheduleWithTimeout() {
+	// Reusable workers let Schedule return while every slot is busy.
+	tp := NewDynamicThreadPoolReusable(1, 1, 10*time.Millisecond, WithQuiet(), WithQueueCapacity(1, 1))
+	suite.assert.NotNil(tp)
+	tp.Start()
+
+	var counter atomic.Int32
+	blocker := &blockingTask{started: make(chan struct{}), release: make(chan struct{})}
+	suite.assert.True(tp.Schedule(false, blocker))
+	<-blocker.started
+
+	ok, err := tp.ScheduleWithTimeout(false, &mockTask{id: 1, counter: &counter}, 0)
+	suite.assert.True(ok, "A queue with room accepts the task without waiting")
+	suite.assert.NoError(err)
+
+	ok, err = tp.ScheduleWithTimeout(false, &mockTask{id: 2, counter: &counter}, 0)
+	suite.assert.False(ok)
+	suite.assert.ErrorIs(err, ErrScheduleTimeout, "A zero timeout must not wait for a full queue")
+
+	start := time.Now()
+	ok, err = tp.ScheduleWithTimeout(false, &mockTask{id: 3, counter: &counter}, 50*time.Millisecond)
+	suite.assert.False(ok)
+	suite.assert.ErrorIs(err, ErrScheduleTimeout)
+	suite.assert.GreaterOrEqual(time.Since(start), 50*time.Millisecond)
+
+	// Stop while waiting for queue space returns promptly.
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := tp.ScheduleWithTimeout(false, &mockTask{id: 4, counter: &counter}, time.Minute)
+		errCh <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	go tp.Stop()
+	select {
+	case err := <-errCh:
+		suite.assert.ErrorIs(err, ErrPoolStopped)
+	case <-time.After(time.Second):
+		suite.T().Fatal("ScheduleWithTimeout did not return after Stop")
+	}
+	close(blocker.release)
+	suite.waitForState(tp, StateStopped, time.Second)
+	suite.assert.Equal(uint64(3), tp.Stats().Rejected)
+}
+
+func (suite *DynamicThreadPoolTestSuite) TestWaitIdle() {
+	tp := NewDynamicThreadPool(2, 4, WithQuiet())
+	suite.assert.NotNil(tp)
+	tp.Start()
+
+	suite.assert.NoError(tp.WaitIdle(context.Background()), "A new pool is idle")
+
+	var counter atomic.Int32
+	for phase := 1; phase <= 3; phase++ {
+		for i := 0; i < 20; i++ {
+			suite.assert.True(tp.Schedule(i%4 == 0, &mockTask{id: i, counter: &counter, workTime: time.Millisecond}))
+		}
+		suite.assert.NoError(tp.WaitIdle(context.Background()))
+		suite.assert.Equal(int32(20*phase), counter.Load(), "Every task of phase %d should have run", phase)
+	}
+
+	// Tasks scheduled while waiting extend the wait.
+	var chain func(n int) Task
+	chain = func(n int) Task {
+		return FuncTask(func() {
+			time.Sleep(2 * time.Millisecond)
+			counter.Add(1)
+			if n > 1 {
+				tp.Schedule(false, chain(n-1))
+			}
+		})
+	}
+	suite.assert.True(tp.Schedule(false, chain(10)))
+	suite.assert.NoError(tp.WaitIdle(context.Background()))
+	suite.assert.Equal(int32(70), counter.Load())
+
+	blocker := &blockingTask{started: make(chan struct{}), release: make(chan struct{})}
+	suite.assert.True(tp.Schedule(false, blocker))
+	<-blocker.started
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	suite.assert.ErrorIs(tp.WaitIdle(ctx), context.DeadlineExceeded, "A running task keeps the pool busy")
+	close(blocker.release)
+	suite.assert.NoError(tp.WaitIdle(context.Background()))
+	suite.assert.Equal(uint32(0), tp.GetActiveWorkers())
+
+	tp.Stop()
+}
+
+func (suite *DynamicThreadPoolTestSuite) TestResize() {
+	// Without waiting for slots, Schedule returns while every slot is busy.
+	tp := NewDynamicThreadPool(1, 1, WithQuiet(), WithRejectionPolicy(PolicyDropNewest))
+	suite.assert.NotNil(tp)
+	tp.Start()
+	defer tp.Stop()
+
+	newBlocker := func() *blockingTask {
+		return &blockingTask{started: make(chan struct{}), release: make(chan struct{})}
+	}
+	waitStarted := func(b *blockingTask) {
+		select {
+		case <-b.started:
+		case <-time.After(time.Second):
+			suite.T().Fatal("Task did not start")
+		}
+	}
+	notStarted := func(b *blockingTask) {
+		select {
+		case <-b.started:
+			suite.T().Fatal("Task started above the worker cap")
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	suite.assert.Error(tp.Resize(0, 1))
+	suite.assert.Error(tp.Resize(1, 0))
+
+	// Growing allows more workers straight away.
+	b := []*blockingTask{newBlocker(), newBlocker(), newBlocker(), newBlocker()}
+	suite.assert.True(tp.Schedule(false, b[0]))
+	waitStarted(b[0])
+	suite.assert.NoError(tp.Resize(1, 3))
+	p, n := tp.Limits()
+	suite.assert.Equal(uint32(1), p)
+	suite.assert.Equal(uint32(3), n)
+	suite.assert.True(tp.Schedule(false, b[1]))
+	suite.assert.True(tp.Schedule(false, b[2]))
+	waitStarted(b[1])
+	waitStarted(b[2])
+	suite.assert.Equal(uint32(3), tp.GetActiveWorkers())
+
+	// Shrinking lets the running workers finish but launches no new ones.
+	suite.assert.NoError(tp.Resize(1, 1))
+	_, n = tp.Limits()
+	suite.assert.Equal(uint32(1), n)
+	suite.assert.True(tp.Schedule(false, b[3]))
+	notStarted(b[3])
+	close(b[0].release) // Its worker picks up b[3] instead of exiting.
+	waitStarted(b[3])
+	close(b[1].release)
+	close(b[2].release)
+	suite.assert.Eventually(func() bool { return tp.GetActiveWorkers() == 1 }, time.Second, 5*time.Millisecond)
+	close(b[3].release)
+	suite.assert.NoError(tp.WaitIdle(context.Background()))
+
+	// Only one normal worker fits under the new cap.
+	c := []*blockingTask{newBlocker(), newBlocker()}
+	suite.assert.True(tp.Schedule(false, c[0]))
+	waitStarted(c[0])
+	suite.assert.True(tp.Schedule(false, c[1]))
+	notStarted(c[1])
+	suite.assert.Equal(uint32(1), tp.GetActiveWorkers())
+	close(c[0].release)
+	waitStarted(c[1])
+	close(c[1].release)
+	suite.assert.NoError(tp.WaitIdle(context.Background()))
+}
+
+func (suite *DynamicThreadPoolTestSuite) TestResizeWakesWaitingSchedule() {
+	tp := NewDynamicThreadPool(1, 1, WithQuiet())
+	suite.assert.NotNil(tp)
+	tp.Start()
+	defer tp.Stop()
+
+	blocker := &blockingTask{started: make(chan struct{}), release: make(chan struct{})}
+	suite.assert.True(tp.Schedule(false, blocker))
+	<-blocker.started
+	defer close(blocker.release)
+
+	// The second Schedule waits for a normal slot until Resize adds one.
+	next := &blockingTask{started: make(chan struct{}), release: make(chan struct{})}
+	defer close(next.release)
+	go tp.Schedule(false, next)
+	time.Sleep(20 * time.Millisecond)
+	suite.assert.NoError(tp.Resize(1, 2))
+	select {
+	case <-next.started:
+	case <-time.After(time.Second):
+		suite.T().Fatal("Schedule waiting for a slot was not woken up by Resize")
+	}
+}
+
+func (suite *DynamicThreadPoolTestSuite) TestScheduleValue() {
+	tp := NewDynamicThreadPool(2, 4, WithQuiet())
+	suite.assert.NotNil(tp)
+	tp.Start()
+	defer tp.Stop()
+
+	results := make(chan TaskResult[int], 10)
+	for i := 0; i < 10; i++ {
+		id := i
+		suite.assert.True(ScheduleValue(tp, i%3 == 0, func() (int, error) { return id, nil }, results))
+	}
+
+	var ids []int
+	for _, result := range WaitAll(results, 10) {
+		suite.assert.NoError(result.Err)
+		ids = append(ids, result.Value)
+	}
+	slices.Sort(ids)
+	suite.assert.Equal([]int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, ids)
+
+	errBoom := errors.New("boom")
+	suite.assert.True(ScheduleValue(tp, false, func() (int, error) { return 1, errBoom }, results))
+	suite.assert.True(ScheduleValue(tp, false, func() (int, error) { panic("bad block") }, results))
+	failed := WaitAll(results, 2)
+	suite.assert.Len(failed, 2)
+	for _, result := range failed {
+		if errors.Is(result.Err, ErrTaskPanicked) {
+			suite.assert.Zero(result.Value, "A panicking task delivers the zero value")
+			suite.assert.Contains(result.Err.Error(), "bad block")
+		} else {
+			suite.assert.ErrorIs(result.Err, errBoom)
+		}
+	}
+}
+
+func (suite *DynamicThreadPoolTestSuite) TestScheduleFunc() {
+	tp := NewDynamicThreadPool(1, 1, WithQuiet())
+	suite.assert.NotNil(tp)
+	tp.Start()
+
+	var counter atomic.Int32
+	suite.assert.True(tp.ScheduleFunc(true, func() { counter.Add(1) }))
+	suite.assert.True(tp.ScheduleFunc(false, func() { counter.Add(1) }))
+	suite.waitForCounter(2, &counter, time.Second)
+
+	tp.Stop()
+	suite.assert.False(tp.ScheduleFunc(false, func() { counter.Add(1) }), "Stopped pool rejects closures")
+}
+
+func (suite *DynamicThreadPoolTestSuite) TestRetry() {
+	tp := NewDynamicThreadPool(2, 2, WithQuiet(), WithRetry(2, time.Millisecond))
+	suite.assert.NotNil(tp)
+	tp.Start()
+	defer tp.Stop()
+
+	task := newPrefetchTask(2)
+	suite.assert.NoError(tp.ScheduleWithFuture(false, task).Wait(), "Future completes after the last attempt")
+	suite.assert.Equal(int32(3), task.attempts(), "Should succeed on the third attempt")
+	suite.assert.NoError(task.Err())
+
+	// Retries run out before this one can succeed.
+	hopeless := newPrefetchTask(5)
+	suite.assert.NoError(tp.ScheduleWithFuture(true, hopeless).Wait())
+	suite.assert.Equal(int32(3), hopeless.attempts(), "One run plus two retries")
+	suite.assert.ErrorIs(hopeless.Err(), ErrPrefetchFailed)
+	suite.assert.NoError(tp.WaitIdle(context.Background()))
+}
+
+func (suite *DynamicThreadPoolTestSuite) TestIdentifiableTaskLogs() {
+	logs := captureLogs(suite.T())
+	tp := NewDynamicThreadPool(1, 1, WithLogger(log.Default()))
+	suite.assert.NotNil(tp)
+	tp.Start()
+
+	future := tp.ScheduleWithFuture(true, &idTask{id: "req-42"})
+	suite.assert.NoError(future.Wait())
+	suite.assert.NoError(tp.ScheduleWithFuture(false, FuncTask(func() {})).Wait())
+	tp.Stop()
+
+	out := logs.String()
+	suite.assert.Contains(out, `Launched priority worker for task "req-42".`)
+	suite.assert.Contains(out, `Starting priority task "req-42"`)
+	suite.assert.Contains(out, `Finished priority task "req-42"`)
+	suite.assert.Contains(out, "Launched normal worker. Active count", "Plain tasks are logged as before")
+	suite.assert.Equal(3, strings.Count(out, `"req-42"`), "Only the identifiable task is named")
+}
+
 // --- Helper Methods ---
 
+// waitForState polls the pool state until it equals want or times out.
+func (suite *DynamicThreadPoolTestSuite) waitForState(tp *DynamicThreadPool, want PoolState, timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for tp.State() != want {
+		if time.Now().After(deadline) {
+			suite.assert.Failf("Timeout", "Timed out waiting for state %v, got %v", want, tp.State())
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// lockedBuffer is a bytes.Buffer safe for concurrent use as a log sink.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// captureLogs redirects the standard logger into a buffer for the rest of the test.
+func captureLogs(t *testing.T) *lockedBuffer {
+	logs := &lockedBuffer{}
+	log.SetOutput(logs)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	return logs
+}
+
 // waitForCounter polls an atomic counter until it reaches the target value or times out.
 func (suite *DynamicThreadPoolTestSuite) waitForCounter(target int32, counter *atomic.Int32, timeout time.Duration) {
 	startTime := time.Now()
@@ -275,6 +1799,42 @@ func (suite *DynamicThreadPoolTestSuite) waitForCounter(target int32, counter *a
 	}
 }
 
+// --- Benchmarks ---
+
+// benchmarkScheduling mirrors BenchmarkDynamicPoolScheduling in the experiment
+// package: it schedules b.N trivial tasks and waits for them to finish.
+func benchmarkScheduling(b *testing.B, tp *DynamicThreadPool) {
+	tp.Start()
+	defer tp.Stop()
+
+	var wg sync.WaitGroup
+	wg.Add(b.N)
+	task := FuncTask(func() { wg.Done() })
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tp.Schedule(false, task)
+	}
+	wg.Wait()
+}
+
+func BenchmarkDynamicThreadPoolScheduling(b *testing.B) {
+	benchmarkScheduling(b, NewDynamicThreadPool(20, 200, WithQuiet()))
+}
+
+func BenchmarkDynamicThreadPoolReusableScheduling(b *testing.B) {
+	benchmarkScheduling(b, NewDynamicThreadPoolReusable(20, 200, time.Second, WithQuiet()))
+}
+
+/**
+Command: go test -run xxx -bench DynamicThreadPool -benchmem
+
+Output:
+BenchmarkDynamicThreadPoolScheduling            1000000              1159 ns/op              16 B/op          1 allocs/op
+BenchmarkDynamicThreadPoolReusableScheduling    7083177               177.5 ns/op             0 B/op          0 allocs/op
+
+Conclusion: reusing workers removes the per-task goroutine cost of scheduling.
+*/
+
 // --- Test Runner ---
 
 func TestDynamicThreadPoolSuite(t *testing.T) {
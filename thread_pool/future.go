@@ -0,0 +1,84 @@
+package thread_pool
+
+import (
+	"context"
+	"sync"
+)
+
+// job is a task queued on a DynamicThreadPool together with the bookkeeping
+// the pool keeps for it until it has run.
+type job struct {
+	ctx     context.Context // Task is skipped once ctx is done; never nil.
+	task    Task
+	future  *TaskFuture // Completed once task has run; nil if nobody waits.
+	started *TaskFuture // Completed once a run of task begins; nil if nobody waits.
+	urgent  bool        // Queue the job was scheduled on, for retries.
+	attempt int         // Number of earlier runs of task, for retries.
+}
+
+// Execute runs the task, unless its context is done, and completes the
+// future, so a job can leave the pool as a plain Task, e.g. when its queue is
+// migrated to another pool.
+func (j job) Execute() {
+	j.started.complete(nil)
+	if err := j.ctx.Err(); err != nil {
+		j.future.complete(err)
+		return
+	}
+	defer j.future.complete(nil)
+	j.task.Execute()
+}
+
+// TaskFuture is a handle on a single scheduled task that completes when the
+// task's Execute returns or the pool rejects the task. All methods are safe
+// for concurrent use.
+type TaskFuture struct {
+	done chan struct{}
+	once sync.Once
+	err  error // Written once before done is closed.
+}
+
+// newTaskFuture returns a future that has not completed yet.
+func newTaskFuture() *TaskFuture {
+	return &TaskFuture{done: make(chan struct{})}
+}
+
+// complete resolves the future with err. Only the first call has an effect,
+// and it is a no-op on a nil future.
+func (f *TaskFuture) complete(err error) {
+	if f == nil {
+		return
+	}
+	f.once.Do(func() {
+		f.err = err
+		close(f.done)
+	})
+}
+
+// Done returns a channel that is closed once the future completes.
+func (f *TaskFuture) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait blocks until the future completes and returns Err.
+func (f *TaskFuture) Wait() error {
+	<-f.done
+	return f.err
+}
+
+// Err returns why the task did not run to completion: ErrPoolStopped or
+// ErrTaskInFlight if the pool rejected it, the context error if it was
+// skipped because its context was done, or an error wrapping ErrTaskPanicked if
+// Execute panicked. It returns nil if the task ran, if
+// the future has not completed yet, or if f is nil.
+func (f *TaskFuture) Err() error {
+	if f == nil {
+		return nil
+	}
+	select {
+	case <-f.done:
+		return f.err
+	default:
+		return nil
+	}
+}
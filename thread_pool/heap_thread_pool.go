@@ -0,0 +1,271 @@
+package thread_pool
+
+import (
+	"container/heap"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// HeapThreadPool is a variant of DynamicThreadPool that keeps pending tasks
+// in a single queue ordered by a numeric priority instead of two channels.
+// Tasks with a lower priority value run first, and tasks with equal priority
+// run in the order they were scheduled, so the execution order is
+// deterministic with a single worker. Like DynamicThreadPool, workers are
+// created on demand up to a limit and exit once the queue is empty, or after
+// waiting up to keepAlive for more work if the pool is reusable. Idle workers
+// sleep on a condition variable. Until Start, tasks run on the calling
+// goroutine, like StaticThreadPool, as nothing else would take them.
+type HeapThreadPool struct {
+	maxWorkers uint32        // Max concurrent workers.
+	keepAlive  time.Duration // How long an idle worker waits for more work; 0 means it exits at once.
+
+	mu      sync.Mutex // Guards the fields below.
+	cond    *sync.Cond // Signalled when a task is queued or the pool stops.
+	queue   taskHeap   // Pending tasks, lowest priority value first.
+	seq     uint64     // Scheduling order, used to break priority ties.
+	started bool       // Set by Start; until then tasks run inline.
+	stopped bool       // Set by Stop; later tasks are rejected.
+	workers uint32     // Worker goroutines running.
+	idle    uint32     // Workers waiting on cond for a task.
+
+	wg       sync.WaitGroup // Waits for all workers to finish.
+	busy     atomic.Uint32  // Number of workers executing a task.
+	stopOnce sync.Once      // Ensures Stop logic runs only once.
+
+	opts poolOptions // Optional behaviour configured at construction.
+}
+
+// heapEntry is a task waiting in a HeapThreadPool.
+type heapEntry struct {
+	task     Task
+	priority int
+	seq      uint64
+}
+
+// taskHeap implements heap.Interface, ordering entries by priority and then
+// by scheduling order.
+type taskHeap []heapEntry
+
+func (h taskHeap) Len() int { return len(h) }
+
+func (h taskHeap) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority < h[j].priority
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h taskHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *taskHeap) Push(x any) { *h = append(*h, x.(heapEntry)) }
+
+func (h *taskHeap) Pop() any {
+	old := *h
+	n := len(old)
+	entry := old[n-1]
+	old[n-1] = heapEntry{} // Drop the task reference.
+	*h = old[:n-1]
+	return entry
+}
+
+// NewHeapThreadPool creates a pool of up to maxWorkers workers sharing one
+// priority ordered queue. maxWorkers must be > 0.
+// opts: Optional settings, e.g. WithQuiet().
+func NewHeapThreadPool(maxWorkers uint32, opts ...Option) *HeapThreadPool {
+	options := newPoolOptions(opts)
+	if maxWorkers == 0 {
+		options.errorf("HeapThreadPool: maxWorkers cannot be zero\n")
+		return nil
+	}
+	if name := options.unsupported(); name != "" {
+		options.errorf("HeapThreadPool: %s is not supported\n", name)
+		return nil
+	}
+	options.debugf("HeapThreadPool: Creating with maxWorkers: %d\n", maxWorkers)
+
+	t := &HeapThreadPool{
+		maxWorkers: maxWorkers,
+		opts:       options,
+	}
+	t.cond = sync.NewCond(&t.mu)
+	return t
+}
+
+// NewHeapThreadPoolReusable creates a heap pool whose workers are reused, like
+// NewDynamicThreadPoolReusable: a worker that runs out of tasks waits up to
+// keepAlive for more before it exits. keepAlive must be > 0.
+func NewHeapThreadPoolReusable(maxWorkers uint32, keepAlive time.Duration, opts ...Option) *HeapThreadPool {
+	if keepAlive <= 0 {
+		options := newPoolOptions(opts)
+		options.errorf("HeapThreadPool: keepAlive must be positive\n")
+		return nil
+	}
+	t := NewHeapThreadPool(maxWorkers, opts...)
+	if t != nil {
+		t.keepAlive = keepAlive
+	}
+	return t
+}
+
+// Start lets the pool queue tasks. No workers are started initially; they are
+// created per task.
+func (t *HeapThreadPool) Start() {
+	t.mu.Lock()
+	t.started = true
+	t.mu.Unlock()
+	register(t)
+	t.opts.debugf("HeapThreadPool: Started. Workers will be created per task.\n")
+}
+
+// Schedule queues item ahead of every task scheduled with ScheduleWithPriority
+// if urgent, and behind all of them otherwise. Returns false if the pool is
+// stopped.
+func (t *HeapThreadPool) Schedule(urgent bool, item Task) bool {
+	if urgent {
+		return t.ScheduleWithPriority(math.MinInt, item)
+	}
+	return t.ScheduleWithPriority(math.MaxInt, item)
+}
+
+// ScheduleWithPriority queues item to run before every pending task with a
+// higher priority value and after those scheduled earlier with the same
+// value, and launches a worker for it unless an idle one can take it or
+// maxWorkers are running. Returns false if the pool is stopped. It never
+// blocks, as the queue is unbounded. Before Start, item runs on the calling
+// goroutine instead.
+func (t *HeapThreadPool) ScheduleWithPriority(priority int, item Task) bool {
+	t.mu.Lock()
+	if t.stopped {
+		t.mu.Unlock()
+		return false
+	}
+	if !t.started {
+		t.mu.Unlock()
+		t.runTask(item)
+		return true
+	}
+	defer t.mu.Unlock()
+	heap.Push(&t.queue, heapEntry{task: item, priority: priority, seq: t.seq})
+	t.seq++
+	if int(t.idle) < t.queue.Len() && t.workers < t.maxWorkers {
+		t.workers++
+		t.wg.Add(1)
+		go t.workerTask()
+		t.opts.debugf("HeapThreadPool: Launched worker%s. Running count: %d\n", forTask(item), t.workers)
+	}
+	t.cond.Signal()
+	return true
+}
+
+// Pending returns the number of queued tasks that have not started yet.
+func (t *HeapThreadPool) Pending() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.queue.Len()
+}
+
+// workerTask runs queued tasks, in priority order, until the queue is empty or
+// the pool stops.
+func (t *HeapThreadPool) workerTask() {
+	defer t.wg.Done()
+	for {
+		task, ok := t.nextTask()
+		if !ok {
+			return
+		}
+		t.runTask(task)
+	}
+}
+
+// nextTask removes the next task from the queue. If the queue is empty it
+// returns false straight away, or, for reusable workers, once no task arrived
+// within keepAlive. It also returns false once the pool stops. A worker that
+// gets false has been counted out of the running workers.
+func (t *HeapThreadPool) nextTask() (Task, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.queue.Len() == 0 && !t.stopped && t.keepAlive > 0 {
+		deadline := time.Now().Add(t.keepAlive)
+		wake := time.AfterFunc(t.keepAlive, func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.cond.Broadcast()
+		})
+		defer wake.Stop()
+		t.idle++
+		for t.queue.Len() == 0 && !t.stopped && time.Now().Before(deadline) {
+			t.cond.Wait()
+		}
+		t.idle--
+	}
+	if t.stopped || t.queue.Len() == 0 {
+		t.workers--
+		return nil, false
+	}
+	return heap.Pop(&t.queue).(heapEntry).task, true
+}
+
+// runTask executes task, recovering a panic so the worker, or the caller
+// before Start, keeps going.
+func (t *HeapThreadPool) runTask(task Task) {
+	t.busy.Add(1)
+	defer t.busy.Add(^uint32(0))
+	defer func() {
+		if r := recover(); r != nil {
+			t.opts.errorf("HeapThreadPool: Recovered panic in task %T: %v\n", task, r)
+			if t.opts.panicHandler != nil {
+				t.opts.panicHandler(r)
+			}
+		}
+	}()
+	task.Execute()
+}
+
+// Stop signals workers to terminate and waits for currently executing tasks
+// to finish. Tasks still queued are not executed.
+func (t *HeapThreadPool) Stop() {
+	t.stopOnce.Do(func() {
+		t.opts.debugf("HeapThreadPool: Stopping...\n")
+		t.mu.Lock()
+		t.stopped = true
+		t.queue = nil
+		t.cond.Broadcast()
+		t.mu.Unlock()
+
+		t.wg.Wait()
+		deregister(t)
+		t.opts.debugf("HeapThreadPool: Pool stopped completely.\n")
+	})
+}
+
+// GetActiveWorkers returns the number of workers executing a task right now.
+func (t *HeapThreadPool) GetActiveWorkers() uint32 {
+	return t.busy.Load()
+}
+
+// GetRunningWorkers returns the number of worker goroutines currently running,
+// whether or not they have a task.
+func (t *HeapThreadPool) GetRunningWorkers() uint32 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.workers
+}
+
+// hasStopped implements registeredPool.
+func (t *HeapThreadPool) hasStopped() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.stopped
+}
+
+// info implements registeredPool. All pending tasks are reported as normal.
+func (t *HeapThreadPool) info() PoolInfo {
+	return PoolInfo{
+		Name:          t.opts.name,
+		Kind:          "heap",
+		ActiveWorkers: t.GetActiveWorkers(),
+		QueuedNormal:  t.Pending(),
+	}
+}
@@ -0,0 +1,173 @@
+package thread_pool
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type HeapThreadPoolTestSuite struct {
+	suite.Suite
+	assert *assert.Assertions
+}
+
+func (suite *HeapThreadPoolTestSuite) SetupTest() {
+	suite.assert = assert.New(suite.T())
+}
+
+// blockWorker occupies the only worker of tp until the returned channel is
+// closed, so tasks scheduled meanwhile stay queued.
+func (suite *HeapThreadPoolTestSuite) blockWorker(tp *HeapThreadPool) chan struct{} {
+	started, release := make(chan struct{}), make(chan struct{})
+	suite.assert.True(tp.ScheduleWithPriority(0, FuncTask(func() {
+		close(started)
+		<-release
+	})))
+	<-started
+	return release
+}
+
+func (suite *HeapThreadPoolTestSuite) TestCreate() {
+	suite.assert.Nil(NewHeapThreadPool(0), "Should fail without workers")
+	suite.assert.Nil(NewHeapThreadPoolReusable(1, 0), "Should fail without keepAlive")
+
+	tp := NewHeapThreadPool(2, WithQuiet())
+	suite.assert.NotNil(tp)
+	tp.Start()
+	suite.assert.Equal(uint32(0), tp.GetRunningWorkers(), "Workers are created per task")
+	tp.Stop()
+	suite.assert.False(tp.ScheduleWithPriority(1, FuncTask(func() {})), "Stopped pool rejects tasks")
+}
+
+func (suite *HeapThreadPoolTestSuite) TestRunsInlineBeforeStart() {
+	tp := NewHeapThreadPool(1, WithQuiet())
+	suite.assert.NotNil(tp)
+	defer tp.Stop()
+
+	var counter atomic.Int32
+	suite.assert.True(tp.ScheduleWithPriority(1, FuncTask(func() { counter.Add(1) })))
+	suite.assert.True(tp.Schedule(true, FuncTask(func() { counter.Add(1) })))
+	suite.assert.Equal(int32(2), counter.Load(), "Tasks should run on the caller before Start")
+	suite.assert.Equal(0, tp.Pending())
+	suite.assert.Equal(uint32(0), tp.GetRunningWorkers())
+}
+
+func (suite *HeapThreadPoolTestSuite) TestScaling() {
+	tp := NewHeapThreadPool(2, WithQuiet())
+	suite.assert.NotNil(tp)
+	tp.Start()
+	defer tp.Stop()
+
+	var started sync.WaitGroup
+	release := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		started.Add(1)
+		suite.assert.True(tp.ScheduleWithPriority(i, FuncTask(func() {
+			started.Done()
+			<-release
+		})))
+	}
+	suite.assert.Eventually(func() bool { return tp.GetActiveWorkers() == 2 }, time.Second, time.Millisecond)
+	suite.assert.Equal(uint32(2), tp.GetRunningWorkers(), "Should not exceed maxWorkers")
+	suite.assert.Equal(2, tp.Pending())
+
+	close(release)
+	started.Wait()
+	suite.assert.Eventually(func() bool { return tp.GetRunningWorkers() == 0 }, time.Second, time.Millisecond,
+		"Workers should exit once the queue is empty")
+	suite.assert.Equal(uint32(0), tp.GetActiveWorkers())
+}
+
+func (suite *HeapThreadPoolTestSuite) TestReusable() {
+	const keepAlive = 50 * time.Millisecond
+	tp := NewHeapThreadPoolReusable(2, keepAlive, WithQuiet())
+	suite.assert.NotNil(tp)
+	tp.Start()
+	defer tp.Stop()
+
+	done := make(chan struct{}, 2)
+	suite.assert.True(tp.ScheduleWithPriority(0, FuncTask(func() { done <- struct{}{} })))
+	<-done
+	suite.assert.Equal(uint32(1), tp.GetRunningWorkers(), "The worker should wait for more work")
+	suite.assert.Eventually(func() bool { return tp.GetActiveWorkers() == 0 }, time.Second, time.Millisecond)
+
+	// The idle worker takes the next task instead of a new one being launched.
+	suite.assert.True(tp.ScheduleWithPriority(0, FuncTask(func() { done <- struct{}{} })))
+	<-done
+	suite.assert.Equal(uint32(1), tp.GetRunningWorkers())
+
+	suite.assert.Eventually(func() bool { return tp.GetRunningWorkers() == 0 }, time.Second, 5*time.Millisecond,
+		"The worker should exit after keepAlive")
+}
+
+func (suite *HeapThreadPoolTestSuite) TestPriorityOrder() {
+	tp := NewHeapThreadPool(1, WithQuiet())
+	suite.assert.NotNil(tp)
+	tp.Start()
+	defer tp.Stop()
+	release := suite.blockWorker(tp)
+
+	var mu sync.Mutex
+	var order []int
+	var wg sync.WaitGroup
+	record := func(id int) Task {
+		wg.Add(1)
+		return FuncTask(func() {
+			defer wg.Done()
+			mu.Lock()
+			order = append(order, id)
+			mu.Unlock()
+		})
+	}
+
+	// The id of each task is the position it should run at.
+	suite.assert.True(tp.ScheduleWithPriority(5, record(6)))
+	suite.assert.True(tp.ScheduleWithPriority(2, record(2)))
+	suite.assert.True(tp.Schedule(false, record(8)))
+	suite.assert.True(tp.ScheduleWithPriority(-3, record(1)))
+	suite.assert.True(tp.ScheduleWithPriority(3, record(4)))
+	suite.assert.True(tp.ScheduleWithPriority(2, record(3)), "Equal priorities run in scheduling order")
+	suite.assert.True(tp.ScheduleWithPriority(4, record(5)))
+	suite.assert.True(tp.Schedule(true, record(0)))
+	suite.assert.True(tp.ScheduleWithPriority(5, record(7)))
+	suite.assert.Equal(9, tp.Pending())
+
+	close(release)
+	wg.Wait()
+	suite.assert.Equal([]int{0, 1, 2, 3, 4, 5, 6, 7, 8}, order)
+	suite.assert.Equal(0, tp.Pending())
+}
+
+func (suite *HeapThreadPoolTestSuite) TestStopDiscardsPending() {
+	var panics atomic.Int32
+	tp := NewHeapThreadPool(1, WithQuiet(), WithPanicHandler(func(any) { panics.Add(1) }))
+	suite.assert.NotNil(tp)
+	tp.Start()
+
+	suite.assert.True(tp.ScheduleWithPriority(0, FuncTask(func() { panic("bad task") })))
+	suite.assert.Eventually(func() bool { return panics.Load() == 1 }, time.Second, 5*time.Millisecond)
+
+	release := suite.blockWorker(tp)
+	var counter atomic.Int32
+	for i := 0; i < 5; i++ {
+		suite.assert.True(tp.ScheduleWithPriority(i, FuncTask(func() { counter.Add(1) })))
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		tp.Stop()
+		close(stopped)
+	}()
+	suite.assert.Eventually(func() bool { return tp.Pending() == 0 }, time.Second, 5*time.Millisecond)
+	close(release)
+	<-stopped
+	suite.assert.Equal(int32(0), counter.Load(), "Queued tasks are discarded by Stop")
+}
+
+func TestHeapThreadPoolSuite(t *testing.T) {
+	suite.Run(t, new(HeapThreadPoolTestSuite))
+}
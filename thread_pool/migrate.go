@@ -0,0 +1,91 @@
+package thread_pool
+
+import (
+	"context"
+)
+
+// MigrateToDynamic switches from a running static pool to a new dynamic pool
+// without dropping work. The dynamic pool is created with the given limits and
+// options and started, then src is stopped: tasks already running on src
+// finish there, and tasks still queued on src are scheduled on the dynamic
+// pool with their original priority. Tasks pinned to a worker with
+// ScheduleToWorker are moved as normal tasks, the ones waiting for a busy
+// worker already before src is stopped. Tasks the dynamic pool rejects, e.g.
+// because its queue is full under PolicyDropNewest, are returned so the
+// caller can run or reschedule them; every other task runs exactly once.
+//
+// Callers must stop scheduling on src before migrating. If the dynamic pool
+// cannot be created, src is left running and nil is returned.
+func MigrateToDynamic(src *StaticThreadPool, maxPriorityWorkers, maxNormalWorkers uint32, opts ...Option) (*DynamicThreadPool, []Task) {
+	dst := NewDynamicThreadPool(maxPriorityWorkers, maxNormalWorkers, opts...)
+	if dst == nil {
+		return nil, nil
+	}
+	dst.Start()
+
+	var rejected []Task
+	move := func(urgent bool, item Task) {
+		if !dst.Schedule(urgent, item) {
+			rejected = append(rejected, item)
+		}
+	}
+	// Pinned tasks would otherwise wait for their worker to finish whatever
+	// it is running; ones it takes meanwhile run on src as usual.
+	for _, ch := range src.workerCh {
+		for drained := false; !drained; {
+			select {
+			case item := <-ch:
+				move(false, item)
+			default:
+				drained = true
+			}
+		}
+	}
+	src.Stop()
+	for item := range src.priorityCh {
+		move(true, item)
+	}
+	for item := range src.normalCh {
+		move(false, item)
+	}
+	for _, ch := range src.workerCh {
+		for item := range ch {
+			move(false, item)
+		}
+	}
+	return dst, rejected
+}
+
+// MigrateToStatic is the reverse of MigrateToDynamic: it creates and starts a
+// static pool with count workers, stops src and schedules the tasks still
+// queued on src on the static pool with their original priority. Tasks the
+// static pool rejects are returned as for MigrateToDynamic, as scheduled on
+// src; a future waiting for one completes with the error the static pool
+// reported.
+//
+// Callers must stop scheduling on src before migrating. If the static pool
+// cannot be created, src is left running and nil is returned.
+func MigrateToStatic(src *DynamicThreadPool, count uint32, opts ...Option) (*StaticThreadPool, []Task) {
+	dst := NewStaticThreadPool(count, opts...)
+	if dst == nil {
+		return nil, nil
+	}
+	dst.Start()
+
+	var rejected []Task
+	move := func(urgent bool, j job) {
+		src.releaseTask(j.task)
+		if err := dst.ScheduleWithContext(context.Background(), urgent, j); err != nil {
+			j.future.complete(err)
+			rejected = append(rejected, j.task)
+		}
+	}
+	src.Stop()
+	for j := range src.priorityCh {
+		move(true, j)
+	}
+	for j := range src.normalCh {
+		move(false, j)
+	}
+	return dst, rejected
+}
@@ -0,0 +1,340 @@
+package thread_pool
+
+import (
+	"slices"
+	"time"
+)
+
+// Option configures optional behaviour of a thread pool. Options are passed
+// to the pool constructors after the required arguments. WithQuiet, WithLogger,
+// WithName and WithPanicHandler are honoured by every pool; the other options
+// say which pools honour them, and the constructors of the remaining pools log
+// an error and return nil rather than silently ignore them.
+type Option func(*poolOptions)
+
+// RejectionPolicy decides what Schedule does with a task whose queue is full.
+type RejectionPolicy int
+
+const (
+	// PolicyBlock makes Schedule wait until the queue has room. This is the
+	// default.
+	PolicyBlock RejectionPolicy = iota
+	// PolicyDropNewest rejects the task being scheduled with ErrQueueFull.
+	PolicyDropNewest
+	// PolicyCallerRuns executes the task on the scheduling goroutine before
+	// Schedule returns.
+	PolicyCallerRuns
+)
+
+// Logger receives the log lines of a pool. *log.Logger implements it; for
+// log/slog, slog.NewLogLogger adapts a handler.
+type Logger interface {
+	Printf(format string, args ...any)
+}
+
+// nopLogger discards everything, so pools are silent unless given a Logger.
+type nopLogger struct{}
+
+// Printf implements Logger.
+func (nopLogger) Printf(string, ...any) {}
+
+// poolOptions holds the optional settings shared by both pool types.
+type poolOptions struct {
+	// logger receives the pool's logs. It discards them unless WithLogger is
+	// used.
+	logger Logger
+
+	// quiet suppresses informational lifecycle logs (creation, start, stop and
+	// per-worker launch/finish lines). Error logs are always emitted.
+	quiet bool
+
+	// executeOnce rejects scheduling a task pointer that is already queued or
+	// running in the pool.
+	executeOnce bool
+
+	// reservedPriority is the number of always-running workers dedicated to
+	// priority tasks.
+	reservedPriority uint32
+
+	// ratePerSecond caps how many tasks may start per second; zero means no
+	// limit. rateBurst is how many may start back to back.
+	ratePerSecond int
+	rateBurst     int
+
+	// inlineUrgent runs an urgent task on the scheduling goroutine when no
+	// priority worker slot is free.
+	inlineUrgent bool
+
+	// recentErrors is how many failed ResultTasks to remember for
+	// RecentErrors; zero disables tracking.
+	recentErrors int
+
+	// workerAffinity gives every static worker its own channel for
+	// ScheduleToWorker.
+	workerAffinity bool
+
+	// name identifies the pool in ListPools.
+	name string
+
+	// strictPriority makes shared static workers take queued priority tasks
+	// before normal ones.
+	strictPriority bool
+
+	// panicHandler is called with the value recovered from a panicking task.
+	panicHandler func(recovered any)
+
+	// priorityQueue and normalQueue override the default queue capacities
+	// when positive.
+	priorityQueue int
+	normalQueue   int
+
+	// rejectionPolicy decides what happens to a task whose queue is full.
+	rejectionPolicy RejectionPolicy
+
+	// priorityPercent and priorityWorkers override how many static workers
+	// listen only for priority tasks, when the matching flag is set.
+	priorityPercent    uint32
+	priorityPercentSet bool
+	priorityWorkers    uint32
+	priorityWorkersSet bool
+
+	// maxRetries is how many times a failed ResultTask is run again, waiting
+	// retryBackoff, doubled on every attempt, before each run.
+	maxRetries   int
+	retryBackoff time.Duration
+}
+
+// WithQuiet suppresses the per-worker lifecycle logs which are otherwise
+// emitted for every task. Error-level logs are still written.
+func WithQuiet() Option {
+	return func(o *poolOptions) {
+		o.quiet = true
+	}
+}
+
+// WithExecuteOnceGuard makes the pool reject a task pointer that is already
+// queued or running, until that earlier run completes. Identity is by pointer:
+// tasks that are not pointers are never considered duplicates. Only honoured
+// by DynamicThreadPool.
+func WithExecuteOnceGuard() Option {
+	return func(o *poolOptions) {
+		o.executeOnce = true
+	}
+}
+
+// WithReservedPriorityWorkers keeps n workers running from Start until Stop,
+// listening only for priority tasks, so urgent work never waits for a worker to
+// be created. Reserved workers count against maxPriorityWorkers. Only honoured
+// by DynamicThreadPool.
+func WithReservedPriorityWorkers(n uint32) Option {
+	return func(o *poolOptions) {
+		o.reservedPriority = n
+	}
+}
+
+// WithRateLimit caps task throughput at perSecond task starts per second,
+// regardless of the number of workers. Workers wait for a token before running
+// each task. Bursts are limited by WithRateBurst and default to one task.
+// Honoured by StaticThreadPool and DynamicThreadPool.
+func WithRateLimit(perSecond int) Option {
+	return func(o *poolOptions) {
+		o.ratePerSecond = perSecond
+	}
+}
+
+// WithRateBurst allows up to n tasks to start back to back when the pool has
+// been idle. It only has an effect together with WithRateLimit.
+func WithRateBurst(n int) Option {
+	return func(o *poolOptions) {
+		o.rateBurst = n
+	}
+}
+
+// WithInlineUrgentFallback makes Schedule(true, task) run the task on the
+// caller's goroutine when no priority worker slot is free, instead of queueing
+// it. This gives the lowest latency for critical tasks, but the caller is
+// blocked for as long as the task runs. Only honoured by DynamicThreadPool.
+func WithInlineUrgentFallback() Option {
+	return func(o *poolOptions) {
+		o.inlineUrgent = true
+	}
+}
+
+// WithRecentErrors keeps the last n errors reported by ResultTasks run by the
+// pool, available through RecentErrors. Memory is bounded by n; older errors
+// are dropped. Honoured by StaticThreadPool and DynamicThreadPool.
+func WithRecentErrors(n int) Option {
+	return func(o *poolOptions) {
+		o.recentErrors = n
+	}
+}
+
+// WithWorkerAffinity gives every worker its own channel, so tasks can be pinned
+// to a worker with ScheduleToWorker when reproducing worker-specific bugs.
+// Schedule keeps using the shared channels. Only honoured by StaticThreadPool.
+func WithWorkerAffinity() Option {
+	return func(o *poolOptions) {
+		o.workerAffinity = true
+	}
+}
+
+// WithStrictPriority makes workers that serve both queues always take a queued
+// priority task before a normal one, instead of choosing at random when both
+// are waiting. A normal task that is already running is never interrupted, so
+// in a pool with a single worker (count=1, which has no dedicated priority
+// worker) a priority task still waits for the current normal task, but no
+// longer than that. The cost is that normal tasks can starve while priority
+// work keeps arriving. Only honoured by StaticThreadPool.
+func WithStrictPriority() Option {
+	return func(o *poolOptions) {
+		o.strictPriority = true
+	}
+}
+
+// WithPanicHandler registers fn to be called with the recovered value whenever
+// a task panics, e.g. to count or report panics. The pool recovers panics and
+// logs them whether or not a handler is set. fn runs on the worker goroutine.
+func WithPanicHandler(fn func(recovered any)) Option {
+	return func(o *poolOptions) {
+		o.panicHandler = fn
+	}
+}
+
+// WithQueueCapacity sets how many priority and normal tasks may wait for a
+// worker. A value of zero or less keeps the default for that queue:
+// maxPriorityWorkers*2 and maxNormalWorkers*10 for DynamicThreadPool, count*2
+// and count*5000 for StaticThreadPool. Bounding the static normal queue keeps
+// producers that outrun the workers from queueing without limit.
+// Honoured by StaticThreadPool and DynamicThreadPool.
+func WithQueueCapacity(priority, normal int) Option {
+	return func(o *poolOptions) {
+		o.priorityQueue = priority
+		o.normalQueue = normal
+	}
+}
+
+// WithRejectionPolicy sets what Schedule does when a task's queue is full.
+// For DynamicThreadPool, any policy other than PolicyBlock also makes
+// Schedule stop waiting for a worker slot and leave queued tasks to the
+// workers already running. Honoured by StaticThreadPool and DynamicThreadPool.
+func WithRejectionPolicy(policy RejectionPolicy) Option {
+	return func(o *poolOptions) {
+		o.rejectionPolicy = policy
+	}
+}
+
+// WithPriorityPercent dedicates percent of the workers to priority tasks
+// instead of the default 10%. If the share rounds down to zero workers for a
+// non-zero percent, one worker is dedicated anyway, as long as another is left
+// for normal tasks. Only honoured by StaticThreadPool.
+func WithPriorityPercent(percent uint32) Option {
+	return func(o *poolOptions) {
+		o.priorityPercent = percent
+		o.priorityPercentSet = true
+	}
+}
+
+// WithPriorityWorkers dedicates exactly n workers to priority tasks, taking
+// precedence over WithPriorityPercent. At least one worker must be left for
+// normal tasks. Only honoured by StaticThreadPool.
+func WithPriorityWorkers(n uint32) Option {
+	return func(o *poolOptions) {
+		o.priorityWorkers = n
+		o.priorityWorkersSet = true
+	}
+}
+
+// WithRetry reruns a ResultTask whose Err reports a failure, up to
+// maxRetries more times. The first retry waits backoff and every further one
+// waits twice as long as the previous one. A retried task goes back to the
+// queue it came from; tasks scheduled with ScheduleToWorker are retried on the
+// shared normal queue. Retries are dropped once the pool stops, and panicking
+// tasks are not retried. Honoured by StaticThreadPool and DynamicThreadPool.
+func WithRetry(maxRetries int, backoff time.Duration) Option {
+	return func(o *poolOptions) {
+		o.maxRetries = maxRetries
+		o.retryBackoff = backoff
+	}
+}
+
+// WithName sets the name the pool is listed under by ListPools.
+func WithName(name string) Option {
+	return func(o *poolOptions) {
+		o.name = name
+	}
+}
+
+// WithLogger sends the pool's logs to logger, e.g. log.Default(). Without it
+// the pool logs nothing. WithQuiet keeps only the error logs.
+func WithLogger(logger Logger) Option {
+	return func(o *poolOptions) {
+		o.logger = logger
+	}
+}
+
+// newPoolOptions applies opts on top of the default settings.
+func newPoolOptions(opts []Option) poolOptions {
+	var o poolOptions
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&o)
+		}
+	}
+	if o.logger == nil {
+		o.logger = nopLogger{}
+	}
+	return o
+}
+
+// poolSpecificOptions are the options that only some pools honour, with how
+// to tell whether one is set.
+var poolSpecificOptions = []struct {
+	name string
+	set  func(o *poolOptions) bool
+}{
+	{"WithExecuteOnceGuard", func(o *poolOptions) bool { return o.executeOnce }},
+	{"WithReservedPriorityWorkers", func(o *poolOptions) bool { return o.reservedPriority > 0 }},
+	{"WithRateLimit", func(o *poolOptions) bool { return o.ratePerSecond > 0 }},
+	{"WithInlineUrgentFallback", func(o *poolOptions) bool { return o.inlineUrgent }},
+	{"WithRecentErrors", func(o *poolOptions) bool { return o.recentErrors > 0 }},
+	{"WithWorkerAffinity", func(o *poolOptions) bool { return o.workerAffinity }},
+	{"WithStrictPriority", func(o *poolOptions) bool { return o.strictPriority }},
+	{"WithQueueCapacity", func(o *poolOptions) bool { return o.priorityQueue > 0 || o.normalQueue > 0 }},
+	{"WithRejectionPolicy", func(o *poolOptions) bool { return o.rejectionPolicy != PolicyBlock }},
+	{"WithPriorityPercent", func(o *poolOptions) bool { return o.priorityPercentSet }},
+	{"WithPriorityWorkers", func(o *poolOptions) bool { return o.priorityWorkersSet }},
+	{"WithRetry", func(o *poolOptions) bool { return o.maxRetries > 0 }},
+}
+
+// unsupported returns the name of the first pool-specific option set in o
+// that is not among supported, or "" if the pool honours all of them.
+func (o *poolOptions) unsupported(supported ...string) string {
+	for _, opt := range poolSpecificOptions {
+		if opt.set(o) && !slices.Contains(supported, opt.name) {
+			return opt.name
+		}
+	}
+	return ""
+}
+
+// newLimiter returns the rate limiter configured by WithRateLimit, or nil if
+// throughput is not limited.
+func (o *poolOptions) newLimiter() *tokenBucket {
+	if o.ratePerSecond <= 0 {
+		return nil
+	}
+	return newTokenBucket(o.ratePerSecond, o.rateBurst)
+}
+
+// debugf logs an informational message unless the pool is quiet.
+func (o *poolOptions) debugf(format string, args ...any) {
+	if o.quiet {
+		return
+	}
+	o.logger.Printf(format, args...)
+}
+
+// errorf logs an error, even if the pool is quiet.
+func (o *poolOptions) errorf(format string, args ...any) {
+	o.logger.Printf(format, args...)
+}
@@ -0,0 +1,35 @@
+package thread_pool
+
+import (
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+)
+
+// panicStats counts the panics recovered from tasks and keeps the most recent
+// one. The zero value is ready to use.
+type panicStats struct {
+	count atomic.Uint64
+
+	mu    sync.Mutex
+	value any    // Value passed to panic by the latest panicking task.
+	stack string // Stack of the goroutine at the latest panic.
+}
+
+// record stores r as the latest panic. It must be called from the deferred
+// function that recovered r, so the stack still shows where the task panicked.
+func (s *panicStats) record(r any) {
+	stack := string(debug.Stack())
+	s.mu.Lock()
+	s.value, s.stack = r, stack
+	s.mu.Unlock()
+	s.count.Add(1)
+}
+
+// last returns the latest panic value and stack, or nil and "" if no task has
+// panicked.
+func (s *panicStats) last() (any, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.value, s.stack
+}
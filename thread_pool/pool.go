@@ -0,0 +1,26 @@
+package thread_pool
+
+// Pool is the API shared by the thread pools of this package, so code can be
+// written against any of them and the implementation chosen by configuration.
+type Pool interface {
+	// Start prepares the pool to run tasks.
+	Start()
+
+	// Stop stops the pool. Tasks scheduled afterwards are rejected.
+	Stop()
+
+	// Schedule queues item for execution, with priority if urgent is set. It
+	// returns false if the pool rejected item.
+	Schedule(urgent bool, item Task) bool
+
+	// GetActiveWorkers returns the number of workers executing a task right
+	// now. Idle workers waiting for a task are not counted.
+	GetActiveWorkers() uint32
+}
+
+var (
+	_ Pool = (*StaticThreadPool)(nil)
+	_ Pool = (*DynamicThreadPool)(nil)
+	_ Pool = (*TieredThreadPool)(nil)
+	_ Pool = (*HeapThreadPool)(nil)
+)
@@ -0,0 +1,61 @@
+package thread_pool
+
+import (
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPoolImplementations(t *testing.T) {
+	pools := map[string]func() Pool{
+		"static":  func() Pool { return NewStaticThreadPool(2, WithQuiet()) },
+		"dynamic": func() Pool { return NewDynamicThreadPool(1, 2, WithQuiet()) },
+		"tiered":  func() Pool { return NewTieredThreadPool([]uint32{1, 2}, WithQuiet()) },
+		"heap":    func() Pool { return NewHeapThreadPool(2, WithQuiet()) },
+	}
+	for name, newPool := range pools {
+		t.Run(name, func(t *testing.T) {
+			pool := newPool()
+			pool.Start()
+
+			started, release := make(chan struct{}), make(chan struct{})
+			done := make(chan struct{})
+			assert.True(t, pool.Schedule(false, FuncTask(func() {
+				close(started)
+				<-release
+				close(done)
+			})))
+			<-started
+			assert.NotZero(t, pool.GetActiveWorkers())
+			close(release)
+			<-done
+
+			pool.Stop()
+			assert.Eventually(t, func() bool { return pool.GetActiveWorkers() == 0 }, time.Second, 5*time.Millisecond)
+			assert.False(t, pool.Schedule(false, FuncTask(func() {})), "A stopped pool should reject tasks")
+		})
+	}
+}
+
+func TestUnsupportedOptions(t *testing.T) {
+	var logs strings.Builder
+	logger := log.New(&logs, "", 0)
+
+	assert.Nil(t, NewStaticThreadPool(2, WithLogger(logger), WithExecuteOnceGuard()))
+	assert.Nil(t, NewDynamicThreadPool(1, 1, WithLogger(logger), WithWorkerAffinity()))
+	assert.Nil(t, NewDynamicThreadPool(1, 1, WithLogger(logger), WithPriorityPercent(20)))
+	assert.Nil(t, NewTieredThreadPool([]uint32{1}, WithLogger(logger), WithRetry(1, time.Millisecond)))
+	assert.Nil(t, NewHeapThreadPool(1, WithLogger(logger), WithQueueCapacity(1, 1)))
+	assert.Contains(t, logs.String(), "StaticThreadPool: WithExecuteOnceGuard is not supported")
+	assert.Contains(t, logs.String(), "DynamicThreadPool: WithWorkerAffinity is not supported")
+
+	// Options every pool honours are accepted everywhere.
+	common := []Option{WithQuiet(), WithName("common"), WithPanicHandler(func(any) {}), WithLogger(logger)}
+	assert.NotNil(t, NewStaticThreadPool(2, common...))
+	assert.NotNil(t, NewDynamicThreadPool(1, 1, common...))
+	assert.NotNil(t, NewTieredThreadPool([]uint32{1}, common...))
+	assert.NotNil(t, NewHeapThreadPool(1, common...))
+}
@@ -0,0 +1,51 @@
+package thread_pool
+
+import (
+	"sync"
+	"time"
+)
+
+// tokenBucket limits how often tasks may start. Tokens are added at rate per
+// second up to burst, and every task takes one token before it runs.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64   // Tokens added per second.
+	burst  float64   // Maximum number of stored tokens.
+	tokens float64   // Available tokens; negative while callers are waiting.
+	last   time.Time // Time tokens were last replenished.
+}
+
+// newTokenBucket returns a bucket allowing perSecond tasks per second with
+// bursts of up to burst tasks. The bucket starts full.
+func newTokenBucket(perSecond, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   float64(perSecond),
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// wait blocks until the caller may start a task. Each caller reserves a token
+// up front, so waiters are served in the order they arrive. A nil bucket never
+// blocks.
+func (b *tokenBucket) wait() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	b.tokens--
+	deficit := -b.tokens
+	b.mu.Unlock()
+
+	if deficit > 0 {
+		time.Sleep(time.Duration(deficit / b.rate * float64(time.Second)))
+	}
+}
@@ -0,0 +1,85 @@
+package thread_pool
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// TaskError describes a ResultTask that reported an error.
+type TaskError struct {
+	// Label identifies the task: its String() if it implements fmt.Stringer,
+	// otherwise its type.
+	Label string
+
+	// Err is the error returned by the task's Err method.
+	Err error
+
+	// Time is when the failure was recorded.
+	Time time.Time
+}
+
+// errorRing keeps the most recent task errors in a fixed-size ring buffer.
+type errorRing struct {
+	mu      sync.Mutex
+	entries []TaskError // Ring storage, len == capacity.
+	next    int         // Index the next entry is written to.
+	count   int         // Number of valid entries, at most len(entries).
+}
+
+// newErrorRing returns a ring holding up to size errors, or nil if size is not
+// positive.
+func newErrorRing(size int) *errorRing {
+	if size <= 0 {
+		return nil
+	}
+	return &errorRing{entries: make([]TaskError, size)}
+}
+
+// record stores the error of task if it is a ResultTask that failed. It is a
+// no-op on a nil ring.
+func (r *errorRing) record(task Task) {
+	if r == nil {
+		return
+	}
+	rt, ok := task.(ResultTask)
+	if !ok {
+		return
+	}
+	err := rt.Err()
+	if err == nil {
+		return
+	}
+
+	entry := TaskError{Label: taskLabel(task), Err: err, Time: time.Now()}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries[r.next] = entry
+	r.next = (r.next + 1) % len(r.entries)
+	if r.count < len(r.entries) {
+		r.count++
+	}
+}
+
+// snapshot returns the recorded errors, oldest first.
+func (r *errorRing) snapshot() []TaskError {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]TaskError, 0, r.count)
+	start := (r.next - r.count + len(r.entries)) % len(r.entries)
+	for i := 0; i < r.count; i++ {
+		out = append(out, r.entries[(start+i)%len(r.entries)])
+	}
+	return out
+}
+
+// taskLabel returns a short human readable name for task.
+func taskLabel(task Task) string {
+	if s, ok := task.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("%T", task)
+}
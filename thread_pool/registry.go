@@ -0,0 +1,77 @@
+package thread_pool
+
+import (
+	"slices"
+	"sync"
+)
+
+// PoolInfo describes a live pool as reported by ListPools.
+type PoolInfo struct {
+	// Name given with WithName, empty if none.
+	Name string
+
+	// Kind is "static", "dynamic", "tiered" or "heap".
+	Kind string
+
+	// ActiveWorkers is the number of workers currently executing tasks, as
+	// returned by GetActiveWorkers.
+	ActiveWorkers uint32
+
+	// Tasks waiting in the priority and normal queues.
+	QueuedPriority int
+	QueuedNormal   int
+}
+
+// registeredPool is implemented by the pools tracked in the registry.
+type registeredPool interface {
+	info() PoolInfo
+
+	// hasStopped reports whether Stop has been called. It must be true
+	// before the pool deregisters itself.
+	hasStopped() bool
+}
+
+// registry tracks every pool that has been started and not yet stopped. Pools
+// register in Start rather than when created, so one that is never started
+// does not stay referenced for the life of the process.
+var registry struct {
+	mu    sync.Mutex
+	pools []registeredPool // In start order.
+}
+
+// register adds p to the registry. It is a no-op if p is already registered
+// or has been stopped, so a Start racing with Stop cannot leave p behind.
+func register(p registeredPool) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	if p.hasStopped() || slices.Contains(registry.pools, p) {
+		return
+	}
+	registry.pools = append(registry.pools, p)
+}
+
+// deregister removes p from the registry. It is a no-op if p is not registered.
+func deregister(p registeredPool) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	for i, rp := range registry.pools {
+		if rp == p {
+			registry.pools = append(registry.pools[:i], registry.pools[i+1:]...)
+			return
+		}
+	}
+}
+
+// ListPools returns information about every pool in the process that has been
+// started and not yet stopped, in the order they were started.
+func ListPools() []PoolInfo {
+	registry.mu.Lock()
+	pools := append([]registeredPool(nil), registry.pools...)
+	registry.mu.Unlock()
+
+	infos := make([]PoolInfo, 0, len(pools))
+	for _, p := range pools {
+		infos = append(infos, p.info())
+	}
+	return infos
+}
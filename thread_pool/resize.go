@@ -0,0 +1,74 @@
+package thread_pool
+
+import (
+	"fmt"
+)
+
+// Resize changes the caps on concurrent priority and normal workers without
+// recreating the pool. Growing takes effect immediately, including for
+// Schedule calls already waiting for a slot. Shrinking never interrupts
+// running workers: they finish as usual, and new workers are only launched
+// once enough of them have exited to get below the new cap. Queue capacities
+// are unchanged. Reserved priority workers count against maxPriority, as in
+// NewDynamicThreadPool.
+//
+// The semaphores are buffered channels whose capacity cannot change, so
+// Resize replaces them. Every slot taken from the old semaphore is carried
+// over into the new one, and whatever does not fit under a smaller cap is
+// recorded as debt that exiting workers pay off before freeing a slot. See
+// resizeSemaphore.
+func (t *DynamicThreadPool) Resize(maxPriority, maxNormal uint32) error {
+	if maxPriority == 0 || maxNormal == 0 {
+		return fmt.Errorf("worker caps must be > 0, got priority %d, normal %d", maxPriority, maxNormal)
+	}
+	if t.opts.reservedPriority > maxPriority {
+		return fmt.Errorf("reserved priority workers (%d) cannot exceed maxPriority (%d)",
+			t.opts.reservedPriority, maxPriority)
+	}
+
+	t.semMu.Lock()
+	defer t.semMu.Unlock()
+	if t.isStopped.Load() {
+		return ErrPoolStopped
+	}
+
+	t.prioritySem, t.priorityDebt = resizeSemaphore(t.prioritySem, t.priorityDebt, int(maxPriority))
+	t.normalSem, t.normalDebt = resizeSemaphore(t.normalSem, t.normalDebt, int(maxNormal))
+	t.maxPriorityWorkers, t.maxNormalWorkers = maxPriority, maxNormal
+
+	// Wake up launches waiting on the old semaphores so they retry on the new
+	// ones.
+	close(t.resized)
+	t.resized = make(chan struct{})
+
+	t.opts.debugf("DynamicThreadPool: Resized to maxPriorityWorkers: %d, maxNormalWorkers: %d\n", maxPriority, maxNormal)
+	return nil
+}
+
+// resizeSemaphore returns a semaphore with the given capacity that carries
+// over the slots held in old, along with the new debt: the number of held
+// slots that do not fit. The caller must hold semMu, so that no slot is
+// released meanwhile.
+//
+// old is filled up first. Nobody releases slots to it any more, so once it
+// is full no launch holding a stale reference can take a slot from it, and
+// the slots that were not filled by Resize are exactly those held by workers.
+func resizeSemaphore(old chan struct{}, debt, capacity int) (chan struct{}, int) {
+	free := 0
+fill:
+	for {
+		select {
+		case old <- struct{}{}:
+			free++
+		default:
+			break fill
+		}
+	}
+
+	held := cap(old) - free + debt
+	sem := make(chan struct{}, capacity)
+	for i := 0; i < held && i < capacity; i++ {
+		sem <- struct{}{}
+	}
+	return sem, max(held-capacity, 0)
+}
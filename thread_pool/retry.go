@@ -0,0 +1,48 @@
+package thread_pool
+
+import (
+	"time"
+)
+
+// failed reports whether item is a ResultTask whose last run failed.
+func failed(item Task) bool {
+	rt, ok := item.(ResultTask)
+	return ok && rt.Err() != nil
+}
+
+// canRetry reports whether a task that failed on the given attempt, counting
+// from zero for the first run, may run again.
+func (o *poolOptions) canRetry(attempt int) bool {
+	return attempt < o.maxRetries
+}
+
+// retryDelay returns how long to wait before rerunning a task that failed on
+// the given attempt. The delay doubles with every attempt.
+func (o *poolOptions) retryDelay(attempt int) time.Duration {
+	return o.retryBackoff << attempt
+}
+
+// retryTask is a failed task queued again by a StaticThreadPool created
+// WithRetry. It remembers how many times the task has already run.
+type retryTask struct {
+	task    Task
+	attempt int // Number of earlier runs of task.
+}
+
+// Execute implements the Task interface for retryTask.
+func (r *retryTask) Execute() {
+	r.task.Execute()
+}
+
+// Err implements the ResultTask interface, reporting the wrapped task's error.
+func (r *retryTask) Err() error {
+	if rt, ok := r.task.(ResultTask); ok {
+		return rt.Err()
+	}
+	return nil
+}
+
+// String labels the retry by the task it wraps.
+func (r *retryTask) String() string {
+	return taskLabel(r.task)
+}
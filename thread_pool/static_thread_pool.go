@@ -1,15 +1,41 @@
 package thread_pool
 
 import (
-	"log"
+	"context"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// defaultPriorityPercent is the share of workers that listen only on the high
+// priority channel.
+const defaultPriorityPercent = 10
+
+// StaticPoolConfig is a snapshot of the settings a StaticThreadPool runs with
+type StaticPoolConfig struct {
+	// Number of workers in the pool
+	Workers uint32
+
+	// Percentage of workers dedicated to the high priority channel, and the
+	// resulting number of such workers
+	PriorityPercent uint32
+	PriorityWorkers uint32
+
+	// Capacity of the pending request channels
+	PriorityQueueCapacity int
+	NormalQueueCapacity   int
+}
+
 // StaticThreadPool is a group of workers that can be used to execute a task
 type StaticThreadPool struct {
 	// Number of workers running in this group
 	worker uint32
 
+	// Percentage and number of workers listening only on the high priority
+	// channel
+	priorityPercent uint32
+	highPriority    uint32
+
 	// Channel to close all the workers
 	close chan int
 
@@ -19,68 +45,391 @@ type StaticThreadPool struct {
 	// Channel to hold pending requests
 	priorityCh chan Task
 	normalCh   chan Task
+
+	// Optional behaviour configured at construction
+	opts poolOptions
+
+	// Caps task throughput, nil when unlimited
+	limiter *tokenBucket
+
+	// Recent task errors, nil unless WithRecentErrors is set
+	errors *errorRing
+
+	// Per-worker channels and executed-task counters, nil unless
+	// WithWorkerAffinity is set
+	workerCh       []chan Task
+	workerExecuted []atomic.Uint64
+
+	// Set by the first Start. Until then nothing takes tasks off the queues,
+	// so tasks scheduled before it are run by the caller
+	started atomic.Bool
+
+	// Number of worker goroutines currently running, and how many of them
+	// are executing a task
+	running atomic.Uint32
+	busy    atomic.Uint32
+
+	// Set once Stop begins; later tasks are rejected. Schedule holds stopMu
+	// for reading while it sends, so Stop never closes a channel mid-send
+	stopMu  sync.RWMutex
+	stopped bool
+
+	// Closed as Stop begins, before it waits for stopMu, so that
+	// ScheduleWithContext calls blocked on a full queue give up
+	stopping chan struct{}
+
+	// Ensures the shutdown in Stop and DrainAndStop runs only once
+	stopOnce sync.Once
+
+	// Lifetime count of tasks accepted, finished and rejected
+	scheduled atomic.Uint64
+	completed atomic.Uint64
+	rejected  atomic.Uint64
+
+	// Panics recovered from tasks
+	panics panicStats
 }
 
 // newStaticThreadPool creates a new thread pool
-func NewStaticThreadPool(count uint32) *StaticThreadPool {
-	log.Printf("StaticThreadpool: creating with worker: %d\n", count)
+func NewStaticThreadPool(count uint32, opts ...Option) *StaticThreadPool {
+	options := newPoolOptions(opts)
+	options.debugf("StaticThreadpool: creating with worker: %d\n", count)
 	if count == 0 {
 		return nil
 	}
+	if name := options.unsupported("WithRateLimit", "WithRecentErrors", "WithWorkerAffinity", "WithStrictPriority",
+		"WithQueueCapacity", "WithRejectionPolicy", "WithPriorityPercent", "WithPriorityWorkers", "WithRetry"); name != "" {
+		options.errorf("StaticThreadPool: %s is not supported\n", name)
+		return nil
+	}
 
-	return &StaticThreadPool{
-		worker:     count,
-		close:      make(chan int, count),
-		priorityCh: make(chan Task, count*2),
-		normalCh:   make(chan Task, count*5000),
+	priorityPercent := uint32(defaultPriorityPercent)
+	if options.priorityPercentSet {
+		if options.priorityPercent > 100 {
+			options.errorf("StaticThreadPool: priority percent %d is above 100\n", options.priorityPercent)
+			return nil
+		}
+		priorityPercent = options.priorityPercent
+	}
+	highPriority := (count * priorityPercent) / 100
+	if options.priorityPercentSet && priorityPercent > 0 && highPriority == 0 && count > 1 {
+		// The share rounds down to nothing, but priority work is expected
+		highPriority = 1
+	}
+	if options.priorityWorkersSet {
+		highPriority = options.priorityWorkers
+		priorityPercent = (highPriority * 100) / count
+	}
+	if highPriority > 0 && highPriority >= count {
+		options.errorf("StaticThreadPool: %d priority workers leave none of %d workers for normal tasks\n", highPriority, count)
+		return nil
+	}
+
+	priorityQueue, normalQueue := int(count*2), int(count*5000)
+	if options.priorityQueue > 0 {
+		priorityQueue = options.priorityQueue
+	}
+	if options.normalQueue > 0 {
+		normalQueue = options.normalQueue
 	}
+
+	t := &StaticThreadPool{
+		worker:          count,
+		priorityPercent: priorityPercent,
+		highPriority:    highPriority,
+		close:           make(chan int, count),
+		priorityCh:      make(chan Task, priorityQueue),
+		normalCh:        make(chan Task, normalQueue),
+		opts:            options,
+		limiter:         options.newLimiter(),
+		errors:          newErrorRing(options.recentErrors),
+		stopping:        make(chan struct{}),
+	}
+
+	if options.workerAffinity {
+		// Each worker gets its share of the normal queue capacity
+		t.workerCh = make([]chan Task, count)
+		for i := range t.workerCh {
+			t.workerCh[i] = make(chan Task, 5000)
+		}
+		t.workerExecuted = make([]atomic.Uint64, count)
+	}
+	return t
 }
 
-// Start all the workers and wait till they start receiving requests
+// Start all the workers and wait till they start receiving requests. Later
+// calls have no effect
 func (t *StaticThreadPool) Start() {
-	// 10% threads will listen only on high priority channel
-	highPriority := (t.worker * 10) / 100
+	if !t.started.CompareAndSwap(false, true) {
+		return
+	}
+	register(t)
+
+	// Some threads will listen only on high priority channel, 10% by default
+	highPriority := t.priorityWorkers()
 
 	for i := uint32(0); i < t.worker; i++ {
 		t.wg.Add(1)
-		go t.Do(i < highPriority)
+		go t.Do(i, i < highPriority)
 	}
 }
 
-// Stop all the workers threads
+// priorityWorkers returns the number of workers dedicated to high priority tasks
+func (t *StaticThreadPool) priorityWorkers() uint32 {
+	return t.highPriority
+}
+
+// Config returns a snapshot of the pool settings. The settings do not change
+// after construction, so it is safe to call from any goroutine.
+func (t *StaticThreadPool) Config() StaticPoolConfig {
+	return StaticPoolConfig{
+		Workers:               t.worker,
+		PriorityPercent:       t.priorityPercent,
+		PriorityWorkers:       t.priorityWorkers(),
+		PriorityQueueCapacity: cap(t.priorityCh),
+		NormalQueueCapacity:   cap(t.normalCh),
+	}
+}
+
+// Stop all the workers threads. Tasks still queued may be discarded; use
+// DrainAndStop to run them first. Later calls have no effect
 func (t *StaticThreadPool) Stop() {
-	for i := uint32(0); i < t.worker; i++ {
-		t.close <- 1
+	t.shutdown(false)
+}
+
+// DrainAndStop rejects new tasks, keeps the workers running until every
+// queued task has been executed, and then stops them like Stop. Tasks that
+// are executing when it is called finish as usual
+func (t *StaticThreadPool) DrainAndStop() {
+	t.shutdown(true)
+}
+
+// shutdown implements Stop and DrainAndStop, waiting for the queues to empty
+// first if drain is set
+func (t *StaticThreadPool) shutdown(drain bool) {
+	t.stopOnce.Do(func() {
+		close(t.stopping)
+
+		// Waits for sends in progress; workers are still running to take them
+		t.stopMu.Lock()
+		t.stopped = true
+		t.stopMu.Unlock()
+
+		if drain {
+			t.waitForEmptyQueues()
+		}
+
+		// A worker in the middle of a task sees its close signal once the
+		// task is done
+		for i := uint32(0); i < t.worker; i++ {
+			t.close <- 1
+		}
+
+		t.wg.Wait()
+
+		close(t.close)
+		close(t.priorityCh)
+		close(t.normalCh)
+		for _, ch := range t.workerCh {
+			close(ch)
+		}
+		deregister(t)
+	})
+}
+
+// waitForEmptyQueues blocks until the workers have taken every queued task.
+// Nothing is added once the pool is stopped, so the queues stay empty
+func (t *StaticThreadPool) waitForEmptyQueues() {
+	ticker := time.NewTicker(idlePollInterval)
+	defer ticker.Stop()
+	for t.queued() > 0 {
+		<-ticker.C
 	}
+}
 
-	t.wg.Wait()
+// queued returns the number of tasks waiting in all the pool's channels
+func (t *StaticThreadPool) queued() int {
+	n := len(t.priorityCh) + len(t.normalCh)
+	for _, ch := range t.workerCh {
+		n += len(ch)
+	}
+	return n
+}
 
-	close(t.close)
-	close(t.priorityCh)
-	close(t.normalCh)
+// Schedule the download of a block. Returns false if the pool is stopped, or
+// if the queue is full and the pool was created WithRejectionPolicy
+// (PolicyDropNewest), in which case the task is dropped and counted as
+// rejected. With the default PolicyBlock, Schedule waits for room in a full
+// queue. Before Start, there are no workers to take the task, so it runs on
+// the calling goroutine before Schedule returns. It is safe to call
+// concurrently with Stop.
+func (t *StaticThreadPool) Schedule(urgent bool, item Task) bool {
+	t.stopMu.RLock()
+	defer t.stopMu.RUnlock()
+	return t.scheduleLocked(urgent, item)
 }
 
-// Schedule the download of a block
-func (t *StaticThreadPool) Schedule(urgent bool, item Task) {
+// scheduleLocked does the work of Schedule. The caller must hold stopMu for
+// reading
+func (t *StaticThreadPool) scheduleLocked(urgent bool, item Task) bool {
+	if t.stopped {
+		t.rejected.Add(1)
+		return false
+	}
+	if t.runIfNotStarted(item, urgent) {
+		return true
+	}
+
 	// urgent specifies the priority of this task.
 	// true means high priority and false means low priority
+	queue := t.normalCh
 	if urgent {
-		t.priorityCh <- item
-	} else {
-		t.normalCh <- item
+		queue = t.priorityCh
+	}
+
+	if t.opts.rejectionPolicy == PolicyBlock {
+		t.scheduled.Add(1)
+		queue <- item
+		return true
+	}
+
+	select {
+	case queue <- item:
+		t.scheduled.Add(1)
+		return true
+	default:
+	}
+	if t.opts.rejectionPolicy == PolicyDropNewest {
+		t.rejected.Add(1)
+		return false
+	}
+	// PolicyCallerRuns
+	t.scheduled.Add(1)
+	t.run(item, urgent)
+	return true
+}
+
+// ScheduleWithContext schedules item like Schedule, but a caller waiting for
+// room in a full queue gives up once ctx is done or the pool starts stopping.
+// It returns nil once item is accepted, the error of ctx if ctx ended first,
+// ErrPoolStopped if the pool is stopped, or ErrQueueFull if the queue is full
+// under PolicyDropNewest. PolicyCallerRuns runs item before returning. Tasks
+// that are not accepted are counted as rejected.
+func (t *StaticThreadPool) ScheduleWithContext(ctx context.Context, urgent bool, item Task) error {
+	t.stopMu.RLock()
+	defer t.stopMu.RUnlock()
+	if t.stopped {
+		t.rejected.Add(1)
+		return ErrPoolStopped
 	}
+	if err := ctx.Err(); err != nil {
+		t.rejected.Add(1)
+		return err
+	}
+	if t.runIfNotStarted(item, urgent) {
+		return nil
+	}
+
+	if t.opts.rejectionPolicy != PolicyBlock {
+		if !t.scheduleLocked(urgent, item) {
+			return ErrQueueFull
+		}
+		return nil
+	}
+
+	queue := t.normalCh
+	if urgent {
+		queue = t.priorityCh
+	}
+	select {
+	case queue <- item:
+		t.scheduled.Add(1)
+		return nil
+	case <-ctx.Done():
+		t.rejected.Add(1)
+		return ctx.Err()
+	case <-t.stopping:
+		t.rejected.Add(1)
+		return ErrPoolStopped
+	}
+}
+
+// ScheduleFunc schedules fn like Schedule, wrapped in a FuncTask.
+func (t *StaticThreadPool) ScheduleFunc(urgent bool, fn func()) bool {
+	return t.Schedule(urgent, FuncTask(fn))
+}
+
+// ScheduleToWorker queues item for the worker with the given index, bypassing
+// the shared channels. It is meant for reproducing problems tied to a specific
+// worker and needs WithWorkerAffinity. It returns false if affinity is not
+// enabled, index is out of range or the pool is stopped.
+func (t *StaticThreadPool) ScheduleToWorker(index int, item Task) bool {
+	if index < 0 || index >= len(t.workerCh) {
+		t.opts.errorf("StaticThreadPool: cannot schedule to worker %d, have %d affinity workers\n", index, len(t.workerCh))
+		t.rejected.Add(1)
+		return false
+	}
+	t.stopMu.RLock()
+	defer t.stopMu.RUnlock()
+	if t.stopped {
+		t.rejected.Add(1)
+		return false
+	}
+	if t.runIfNotStarted(item, false) {
+		return true
+	}
+	t.scheduled.Add(1)
+	t.workerCh[index] <- item
+	return true
+}
+
+// runIfNotStarted runs item on the calling goroutine if the pool has not been
+// started, as a queued task would wait for a worker that may never come. It
+// reports whether it did so
+func (t *StaticThreadPool) runIfNotStarted(item Task, urgent bool) bool {
+	if t.started.Load() {
+		return false
+	}
+	t.scheduled.Add(1)
+	t.run(item, urgent)
+	return true
+}
+
+// TotalScheduled returns how many tasks have been accepted since the pool was
+// created.
+func (t *StaticThreadPool) TotalScheduled() uint64 {
+	return t.scheduled.Load()
+}
+
+// TotalRejected returns how many tasks have been rejected since the pool was
+// created, because it was stopped, because its queue was full under
+// PolicyDropNewest or, for ScheduleToWorker, because the worker index was
+// invalid.
+func (t *StaticThreadPool) TotalRejected() uint64 {
+	return t.rejected.Load()
 }
 
 // Do is the core task to be executed by each worker thread
-func (t *StaticThreadPool) Do(priority bool) {
+func (t *StaticThreadPool) Do(index uint32, priority bool) {
+	t.running.Add(1)
 	defer t.wg.Done()
+	defer t.running.Add(^uint32(0))
+
+	// Tasks routed to this worker by ScheduleToWorker. A nil channel never
+	// becomes ready, so without affinity this case is simply ignored.
+	var own chan Task
+	if t.workerCh != nil {
+		own = t.workerCh[index]
+	}
 
 	if priority {
 		// This thread will work only on high priority channel
 		for {
 			select {
 			case item := <-t.priorityCh:
-				item.Execute()
+				t.execute(index, item, true)
+			case item := <-own:
+				t.execute(index, item, false)
 			case <-t.close:
 				return
 			}
@@ -88,14 +437,161 @@ func (t *StaticThreadPool) Do(priority bool) {
 	} else {
 		// This thread will work only on both high and low priority channel
 		for {
+			if t.opts.strictPriority {
+				// Drain queued priority work before picking up a normal task
+				select {
+				case item := <-t.priorityCh:
+					t.execute(index, item, true)
+					continue
+				default:
+				}
+			}
+
 			select {
 			case item := <-t.priorityCh:
-				item.Execute()
+				t.execute(index, item, true)
 			case item := <-t.normalCh:
-				item.Execute()
+				t.execute(index, item, false)
+			case item := <-own:
+				t.execute(index, item, false)
 			case <-t.close:
 				return
 			}
 		}
 	}
 }
+
+// execute runs a single task, taken from the priority queue if urgent, on
+// worker index
+func (t *StaticThreadPool) execute(index uint32, item Task, urgent bool) {
+	t.run(item, urgent)
+	if t.workerExecuted != nil {
+		t.workerExecuted[index].Add(1)
+	}
+}
+
+// run executes a task once the rate limiter allows it. A panicking task is
+// logged and skipped, so the worker keeps serving its queues. A failed task
+// with retries left is scheduled again with the same urgency
+func (t *StaticThreadPool) run(item Task, urgent bool) {
+	defer t.completed.Add(1)
+	defer func() {
+		if r := recover(); r != nil {
+			t.opts.errorf("StaticThreadPool: Recovered panic in task %T: %v\n", item, r)
+			t.panics.record(r)
+			if t.opts.panicHandler != nil {
+				t.opts.panicHandler(r)
+			}
+		}
+	}()
+
+	t.limiter.wait()
+	id, traced := taskID(item)
+	if traced {
+		t.opts.debugf("StaticThreadPool: Starting %s task %q\n", priorityName(urgent), id)
+	}
+	t.busy.Add(1)
+	defer t.busy.Add(^uint32(0))
+	item.Execute()
+	if traced {
+		t.opts.debugf("StaticThreadPool: Finished %s task %q\n", priorityName(urgent), id)
+	}
+	t.errors.record(item)
+
+	if failed(item) {
+		t.retryLater(item, urgent)
+	}
+}
+
+// retryLater schedules a failed task again after the backoff for its attempt,
+// if it has retries left
+func (t *StaticThreadPool) retryLater(item Task, urgent bool) {
+	attempt := 0
+	if retry, ok := item.(*retryTask); ok {
+		item, attempt = retry.task, retry.attempt
+	}
+	if !t.opts.canRetry(attempt) {
+		return
+	}
+
+	delay := t.opts.retryDelay(attempt)
+	retry := &retryTask{task: item, attempt: attempt + 1}
+	t.opts.debugf("StaticThreadPool: Retrying task %T in %v, retry %d of %d\n", item, delay, retry.attempt, t.opts.maxRetries)
+	time.AfterFunc(delay, func() {
+		if !t.Schedule(urgent, retry) {
+			t.opts.errorf("StaticThreadPool: Dropping retry %d of task %T, the pool rejected it\n", retry.attempt, item)
+		}
+	})
+}
+
+// Stats returns a snapshot of the pool counters and current queue depths, e.g.
+// to alert before the queues saturate. The fields are read one by one, so
+// they may be slightly out of step with each other.
+func (t *StaticThreadPool) Stats() PoolStats {
+	return PoolStats{
+		Scheduled:          t.scheduled.Load(),
+		Completed:          t.completed.Load(),
+		Rejected:           t.rejected.Load(),
+		ActiveWorkers:      t.GetActiveWorkers(),
+		PriorityQueueDepth: len(t.priorityCh),
+		NormalQueueDepth:   len(t.normalCh),
+	}
+}
+
+// GetActiveWorkers returns how many workers are executing a task right now,
+// as for the other pools. It is the same count as GetBusyWorkers; workers
+// waiting for a task are counted by GetRunningWorkers
+func (t *StaticThreadPool) GetActiveWorkers() uint32 {
+	return t.GetBusyWorkers()
+}
+
+// GetRunningWorkers returns the number of worker goroutines currently running.
+// Workers run from Start until Stop, whether or not they have a task
+func (t *StaticThreadPool) GetRunningWorkers() uint32 {
+	return t.running.Load()
+}
+
+// GetBusyWorkers returns how many tasks are executing right now. Running
+// workers not counted here are idle, waiting for a task. A task run by the
+// caller under PolicyCallerRuns is counted too while it executes
+func (t *StaticThreadPool) GetBusyWorkers() uint32 {
+	return t.busy.Load()
+}
+
+// hasStopped implements registeredPool
+func (t *StaticThreadPool) hasStopped() bool {
+	select {
+	case <-t.stopping:
+		return true
+	default:
+		return false
+	}
+}
+
+// info implements registeredPool
+func (t *StaticThreadPool) info() PoolInfo {
+	return PoolInfo{
+		Name:           t.opts.name,
+		Kind:           "static",
+		ActiveWorkers:  t.GetActiveWorkers(),
+		QueuedPriority: len(t.priorityCh),
+		QueuedNormal:   len(t.normalCh),
+	}
+}
+
+// PanicCount returns how many tasks have panicked since the pool was created.
+func (t *StaticThreadPool) PanicCount() uint64 {
+	return t.panics.count.Load()
+}
+
+// LastPanic returns the value and goroutine stack of the most recent task
+// panic, or nil and "" if no task has panicked.
+func (t *StaticThreadPool) LastPanic() (value any, stack string) {
+	return t.panics.last()
+}
+
+// RecentErrors returns the most recent errors reported by ResultTasks, oldest
+// first. It returns nil unless the pool was created WithRecentErrors.
+func (t *StaticThreadPool) RecentErrors() []TaskError {
+	return t.errors.snapshot()
+}
@@ -1,6 +1,10 @@
 package thread_pool
 
 import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -56,8 +60,8 @@ func (suite *staticThreadPoolTestSuite) TestSchedule() {
 	suite.assert.NotNil(tp.priorityCh)
 	suite.assert.NotNil(tp.normalCh)
 
-	tp.Schedule(false, &PrefetchTask{failCnt: 1})
-	tp.Schedule(true, &PrefetchTask{failCnt: 1})
+	tp.Schedule(false, newPrefetchTask(1))
+	tp.Schedule(true, newPrefetchTask(1))
 
 	time.Sleep(1 * time.Second)
 	tp.Stop()
@@ -94,6 +98,600 @@ func (suite *staticThreadPoolTestSuite) TestPrioritySchedule() {
 	tp.Stop()
 }
 
+func (suite *staticThreadPoolTestSuite) TestScheduleBeforeStart() {
+	suite.assert = assert.New(suite.T())
+
+	tp := NewStaticThreadPool(2, WithQuiet())
+	suite.assert.NotNil(tp)
+
+	// Without workers the tasks run inline instead of waiting in the queue.
+	var counter atomic.Int32
+	suite.assert.True(tp.ScheduleFunc(false, func() { counter.Add(1) }))
+	suite.assert.True(tp.ScheduleFunc(true, func() { counter.Add(1) }))
+	suite.assert.NoError(tp.ScheduleWithContext(context.Background(), false, FuncTask(func() { counter.Add(1) })))
+	suite.assert.Equal(int32(3), counter.Load())
+	suite.assert.Equal(0, tp.queued())
+
+	tp.Start()
+	tp.Start()
+	suite.assert.Eventually(func() bool { return tp.GetRunningWorkers() == 2 }, time.Second, 5*time.Millisecond)
+	suite.assert.Equal(uint32(2), tp.GetRunningWorkers(), "A second Start should not add workers")
+
+	done := make(chan struct{})
+	suite.assert.True(tp.ScheduleFunc(false, func() { close(done) }))
+	<-done
+	tp.Stop()
+	suite.assert.Equal(uint64(4), tp.Stats().Completed)
+}
+
+func (suite *staticThreadPoolTestSuite) TestQuietSuppressesLogs() {
+	suite.assert = assert.New(suite.T())
+	logs := captureLogs(suite.T())
+
+	tp := NewStaticThreadPool(2, WithQuiet(), WithLogger(log.Default()))
+	suite.assert.NotNil(tp)
+	tp.Start()
+	tp.Schedule(false, &testTask{})
+	time.Sleep(100 * time.Millisecond)
+	tp.Stop()
+
+	suite.assert.Empty(logs.String())
+}
+
+func (suite *staticThreadPoolTestSuite) TestLogger() {
+	suite.assert = assert.New(suite.T())
+	std := captureLogs(suite.T())
+
+	// Pools are silent by default.
+	suite.assert.Nil(NewStaticThreadPool(2, WithPriorityPercent(101)))
+	tp := NewStaticThreadPool(2)
+	suite.assert.NotNil(tp)
+	tp.Start()
+	tp.Stop()
+	suite.assert.Empty(std.String())
+
+	// WithLogger routes both informational and error logs to the given sink.
+	logs := &lockedBuffer{}
+	logger := log.New(logs, "pool: ", 0)
+	suite.assert.Nil(NewStaticThreadPool(2, WithLogger(logger), WithPriorityPercent(101)))
+	tp = NewStaticThreadPool(2, WithLogger(logger))
+	suite.assert.NotNil(tp)
+	tp.Start()
+	tp.Stop()
+	suite.assert.Contains(logs.String(), "pool: StaticThreadPool: priority percent 101 is above 100")
+	suite.assert.Contains(logs.String(), "pool: StaticThreadpool: creating with worker: 2")
+	suite.assert.Empty(std.String())
+}
+
+func (suite *staticThreadPoolTestSuite) TestConfig() {
+	suite.assert = assert.New(suite.T())
+
+	tp := NewStaticThreadPool(20, WithQuiet())
+	suite.assert.NotNil(tp)
+
+	cfg := tp.Config()
+	suite.assert.Equal(uint32(20), cfg.Workers)
+	suite.assert.Equal(uint32(10), cfg.PriorityPercent)
+	suite.assert.Equal(uint32(2), cfg.PriorityWorkers)
+	suite.assert.Equal(40, cfg.PriorityQueueCapacity)
+	suite.assert.Equal(100000, cfg.NormalQueueCapacity)
+
+	tp.Start()
+	suite.assert.Equal(cfg, tp.Config(), "Config should not change once started")
+	tp.Stop()
+}
+
+func (suite *staticThreadPoolTestSuite) TestPriorityRatio() {
+	suite.assert = assert.New(suite.T())
+
+	tests := []struct {
+		name    string
+		count   uint32
+		opts    []Option
+		percent uint32
+		workers uint32
+	}{
+		{"default", 20, nil, 10, 2},
+		{"default rounds down", 5, nil, 10, 0},
+		{"half", 20, []Option{WithPriorityPercent(50)}, 50, 10},
+		{"rounds up to one", 4, []Option{WithPriorityPercent(10)}, 10, 1},
+		{"single worker stays shared", 1, []Option{WithPriorityPercent(10)}, 10, 0},
+		{"zero percent", 4, []Option{WithPriorityPercent(0)}, 0, 0},
+		{"absolute count", 4, []Option{WithPriorityWorkers(3)}, 75, 3},
+		{"absolute count wins", 4, []Option{WithPriorityPercent(50), WithPriorityWorkers(1)}, 25, 1},
+	}
+	for _, tc := range tests {
+		tp := NewStaticThreadPool(tc.count, append(tc.opts, WithQuiet())...)
+		if !suite.assert.NotNil(tp, tc.name) {
+			continue
+		}
+		cfg := tp.Config()
+		suite.assert.Equal(tc.percent, cfg.PriorityPercent, tc.name)
+		suite.assert.Equal(tc.workers, cfg.PriorityWorkers, tc.name)
+	}
+
+	suite.assert.Nil(NewStaticThreadPool(4, WithQuiet(), WithPriorityPercent(101)), "Percent above 100")
+	suite.assert.Nil(NewStaticThreadPool(4, WithQuiet(), WithPriorityPercent(100)), "No worker left for normal tasks")
+	suite.assert.Nil(NewStaticThreadPool(4, WithQuiet(), WithPriorityWorkers(4)), "No worker left for normal tasks")
+}
+
+func (suite *staticThreadPoolTestSuite) TestDedicatedPriorityWorker() {
+	suite.assert = assert.New(suite.T())
+
+	tp := NewStaticThreadPool(2, WithQuiet(), WithPriorityWorkers(1))
+	suite.assert.NotNil(tp)
+	tp.Start()
+	defer tp.Stop()
+
+	// Occupy the shared worker; the dedicated one still serves priority tasks.
+	release := make(chan struct{})
+	started := make(chan struct{})
+	suite.assert.True(tp.ScheduleFunc(false, func() {
+		close(started)
+		<-release
+	}))
+	<-started
+	defer close(release)
+
+	done := make(chan struct{})
+	suite.assert.True(tp.ScheduleFunc(true, func() { close(done) }))
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		suite.T().Fatal("Priority task waited for the busy shared worker")
+	}
+}
+
+func (suite *staticThreadPoolTestSuite) TestRecoverFromPanic() {
+	suite.assert = assert.New(suite.T())
+	logs := captureLogs(suite.T())
+
+	var panics atomic.Int32
+	// A single shared worker: if it died, nothing else would run.
+	tp := NewStaticThreadPool(1, WithQuiet(), WithLogger(log.Default()), WithPanicHandler(func(any) { panics.Add(1) }))
+	suite.assert.NotNil(tp)
+	tp.Start()
+	defer tp.Stop()
+
+	suite.assert.True(tp.ScheduleFunc(false, func() { panic("bad task") }))
+	var wg sync.WaitGroup
+	var counter atomic.Int32
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		suite.assert.True(tp.ScheduleFunc(i%2 == 0, func() {
+			defer wg.Done()
+			counter.Add(1)
+		}))
+	}
+	wg.Wait()
+
+	suite.assert.Equal(int32(5), counter.Load(), "Tasks after the panic should still run")
+	suite.assert.Equal(int32(1), panics.Load())
+	suite.assert.Contains(logs.String(), "Recovered panic in task thread_pool.FuncTask: bad task")
+	suite.assert.Equal(uint32(1), tp.GetRunningWorkers(), "The worker should survive")
+}
+
+func (suite *staticThreadPoolTestSuite) TestPanicStats() {
+	suite.assert = assert.New(suite.T())
+
+	tp := NewStaticThreadPool(1, WithQuiet())
+	suite.assert.NotNil(tp)
+	tp.Start()
+	defer tp.Stop()
+
+	value, stack := tp.LastPanic()
+	suite.assert.Nil(value)
+	suite.assert.Empty(stack)
+
+	for i := 0; i < 3; i++ {
+		suite.assert.True(tp.ScheduleFunc(false, func() { panic(fmt.Sprintf("bad task %d", i)) }))
+	}
+	suite.assert.Eventually(func() bool { return tp.PanicCount() == 3 }, time.Second, 5*time.Millisecond)
+
+	// A single worker runs the normal queue in order.
+	value, stack = tp.LastPanic()
+	suite.assert.Equal("bad task 2", value)
+	suite.assert.Contains(stack, "TestPanicStats")
+}
+
+func (suite *staticThreadPoolTestSuite) TestBoundedQueue() {
+	suite.assert = assert.New(suite.T())
+
+	// newBusyPool returns a single-worker pool whose worker is blocked until
+	// release is closed.
+	newBusyPool := func(opts ...Option) (*StaticThreadPool, chan struct{}) {
+		tp := NewStaticThreadPool(1, append(opts, WithQuiet(), WithQueueCapacity(1, 2))...)
+		suite.assert.NotNil(tp)
+		suite.assert.Equal(2, tp.Config().NormalQueueCapacity)
+		tp.Start()
+		started, release := make(chan struct{}), make(chan struct{})
+		suite.assert.True(tp.ScheduleFunc(false, func() {
+			close(started)
+			<-release
+		}))
+		<-started
+		return tp, release
+	}
+
+	var counter atomic.Int32
+	task := FuncTask(func() { counter.Add(1) })
+
+	// PolicyDropNewest rejects tasks once the queue is full.
+	tp, release := newBusyPool(WithRejectionPolicy(PolicyDropNewest))
+	suite.assert.True(tp.Schedule(false, task))
+	suite.assert.True(tp.Schedule(false, task))
+	suite.assert.False(tp.Schedule(false, task), "Full queue should reject")
+	stats := tp.Stats()
+	suite.assert.Equal(2, stats.NormalQueueDepth)
+	suite.assert.Equal(uint64(3), stats.Scheduled)
+	suite.assert.Equal(uint64(1), stats.Rejected)
+	close(release)
+	suite.assert.Eventually(func() bool { return tp.Stats().Completed == 3 }, time.Second, 5*time.Millisecond)
+	suite.assert.Equal(int32(2), counter.Load())
+	tp.Stop()
+
+	// PolicyBlock, the default, waits for room.
+	tp, release = newBusyPool()
+	suite.assert.True(tp.Schedule(false, task))
+	suite.assert.True(tp.Schedule(false, task))
+	done := make(chan bool)
+	go func() { done <- tp.Schedule(false, task) }()
+	select {
+	case <-done:
+		suite.T().Fatal("Schedule should block while the queue is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	suite.assert.True(<-done)
+	suite.assert.Eventually(func() bool { return counter.Load() == 5 }, time.Second, 5*time.Millisecond)
+	tp.Stop()
+}
+
+func (suite *staticThreadPoolTestSuite) TestScheduleWithContext() {
+	suite.assert = assert.New(suite.T())
+
+	tp := NewStaticThreadPool(1, WithQuiet(), WithQueueCapacity(1, 1))
+	suite.assert.NotNil(tp)
+	tp.Start()
+	started, release := make(chan struct{}), make(chan struct{})
+	suite.assert.NoError(tp.ScheduleWithContext(context.Background(), false, FuncTask(func() {
+		close(started)
+		<-release
+	})))
+	<-started
+
+	var counter atomic.Int32
+	task := FuncTask(func() { counter.Add(1) })
+	suite.assert.NoError(tp.ScheduleWithContext(context.Background(), false, task))
+
+	// The queue is full, so the call waits until the context is cancelled.
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- tp.ScheduleWithContext(ctx, false, task) }()
+	select {
+	case err := <-done:
+		suite.T().Fatalf("ScheduleWithContext returned %v while the queue is full", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	cancel()
+	suite.assert.ErrorIs(<-done, context.Canceled)
+	suite.assert.ErrorIs(tp.ScheduleWithContext(ctx, false, task), context.Canceled)
+
+	// A deadline gives up the same way.
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	suite.assert.ErrorIs(tp.ScheduleWithContext(ctx, false, task), context.DeadlineExceeded)
+	suite.assert.Equal(uint64(3), tp.TotalRejected())
+
+	// Stopping the pool releases a blocked caller.
+	go func() { done <- tp.ScheduleWithContext(context.Background(), false, task) }()
+	time.Sleep(20 * time.Millisecond)
+	go tp.Stop()
+	suite.assert.ErrorIs(<-done, ErrPoolStopped)
+	close(release)
+	suite.assert.Eventually(func() bool { return tp.GetRunningWorkers() == 0 }, time.Second, 5*time.Millisecond)
+	suite.assert.ErrorIs(tp.ScheduleWithContext(context.Background(), false, task), ErrPoolStopped)
+}
+
+func (suite *staticThreadPoolTestSuite) TestGetBusyWorkers() {
+	suite.assert = assert.New(suite.T())
+
+	tp := NewStaticThreadPool(4, WithQuiet())
+	suite.assert.NotNil(tp)
+	tp.Start()
+	defer tp.Stop()
+	suite.assert.Eventually(func() bool { return tp.GetRunningWorkers() == 4 }, time.Second, 5*time.Millisecond)
+	suite.assert.Equal(uint32(0), tp.GetBusyWorkers())
+	suite.assert.Equal(uint32(0), tp.GetActiveWorkers(), "Idle workers are not active")
+
+	var started sync.WaitGroup
+	release := make(chan struct{})
+	for i := 0; i < 3; i++ {
+		started.Add(1)
+		suite.assert.True(tp.ScheduleFunc(false, func() {
+			started.Done()
+			<-release
+		}))
+	}
+	started.Wait()
+	suite.assert.Equal(uint32(3), tp.GetBusyWorkers())
+	suite.assert.Equal(uint32(3), tp.GetActiveWorkers(), "Active workers are the busy ones")
+	suite.assert.Equal(uint32(4), tp.GetRunningWorkers(), "Idle workers are still running")
+
+	close(release)
+	suite.assert.Eventually(func() bool { return tp.GetBusyWorkers() == 0 }, time.Second, 5*time.Millisecond)
+
+	// A panicking task is no longer busy once it has been recovered.
+	suite.assert.True(tp.ScheduleFunc(false, func() { panic("bad task") }))
+	suite.assert.Eventually(func() bool { return tp.Stats().Completed == 4 }, time.Second, 5*time.Millisecond)
+	suite.assert.Equal(uint32(0), tp.GetBusyWorkers())
+}
+
+func (suite *staticThreadPoolTestSuite) TestScheduleBatch() {
+	suite.assert = assert.New(suite.T())
+
+	tp := NewStaticThreadPool(1, WithQuiet(), WithQueueCapacity(1, 2), WithRejectionPolicy(PolicyDropNewest))
+	suite.assert.NotNil(tp)
+	tp.Start()
+	started, release := make(chan struct{}), make(chan struct{})
+	suite.assert.True(tp.ScheduleFunc(false, func() {
+		close(started)
+		<-release
+	}))
+	<-started
+
+	// Only two of the four tasks fit in the queue.
+	var counter atomic.Int32
+	task := FuncTask(func() { counter.Add(1) })
+	batch := []Task{task, task, task, task}
+	accepted, ok := tp.ScheduleBatch(false, batch)
+	suite.assert.Equal(2, accepted)
+	suite.assert.False(ok)
+	suite.assert.Equal(uint64(1), tp.TotalRejected(), "The batch stops at the first rejection")
+
+	close(release)
+	suite.assert.Eventually(func() bool { return counter.Load() == 2 }, time.Second, 5*time.Millisecond)
+	accepted, ok = tp.ScheduleBatch(false, batch[2:])
+	suite.assert.Equal(2, accepted)
+	suite.assert.True(ok)
+	suite.assert.Eventually(func() bool { return counter.Load() == 4 }, time.Second, 5*time.Millisecond)
+
+	tp.Stop()
+	accepted, ok = tp.ScheduleBatch(true, batch)
+	suite.assert.Equal(0, accepted)
+	suite.assert.False(ok)
+}
+
+func (suite *staticThreadPoolTestSuite) TestDrainAndStop() {
+	suite.assert = assert.New(suite.T())
+
+	tp := NewStaticThreadPool(4, WithQuiet())
+	suite.assert.NotNil(tp)
+	tp.Start()
+
+	// Keep a worker mid-task while draining begins.
+	started, release := make(chan struct{}), make(chan struct{})
+	suite.assert.True(tp.ScheduleFunc(false, func() {
+		close(started)
+		<-release
+	}))
+	<-started
+
+	var counter atomic.Int32
+	for i := 0; i < 200; i++ {
+		suite.assert.True(tp.Schedule(i%10 == 0, FuncTask(func() {
+			time.Sleep(100 * time.Microsecond)
+			counter.Add(1)
+		})))
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		tp.DrainAndStop()
+		close(drained)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	suite.assert.False(tp.ScheduleFunc(false, func() {}), "Draining pool rejects new tasks")
+	close(release)
+
+	select {
+	case <-drained:
+	case <-time.After(5 * time.Second):
+		suite.T().Fatal("DrainAndStop did not return")
+	}
+	suite.assert.Equal(int32(200), counter.Load(), "Every queued task should run")
+	suite.assert.NotPanics(tp.Stop, "Stop after DrainAndStop is a no-op")
+}
+
+func (suite *staticThreadPoolTestSuite) TestRetry() {
+	suite.assert = assert.New(suite.T())
+
+	tp := NewStaticThreadPool(2, WithQuiet(), WithRetry(3, time.Millisecond))
+	suite.assert.NotNil(tp)
+	tp.Start()
+	defer tp.Stop()
+
+	task := newPrefetchTask(2)
+	suite.assert.True(tp.Schedule(true, task))
+	// Completed counts every attempt.
+	suite.assert.Eventually(func() bool { return tp.Stats().Completed == 3 }, 2*time.Second, 5*time.Millisecond)
+	suite.assert.Equal(int32(3), task.attempts(), "Should succeed on the third attempt")
+	suite.assert.NoError(task.Err())
+	suite.assert.Equal(uint64(3), tp.Stats().Scheduled)
+}
+
+func (suite *staticThreadPoolTestSuite) TestRateLimit() {
+	suite.assert = assert.New(suite.T())
+
+	const rate = 20 // One task every 50ms.
+	tp := NewStaticThreadPool(4, WithQuiet(), WithRateLimit(rate))
+	suite.assert.NotNil(tp)
+	tp.Start()
+
+	var counter atomic.Int32
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		tp.Schedule(false, FuncTask(func() { counter.Add(1) }))
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	suite.assert.Less(counter.Load(), int32(6), "Rate limit should hold back part of the tasks")
+	for counter.Load() < 6 && time.Since(start) < 3*time.Second {
+		time.Sleep(10 * time.Millisecond)
+	}
+	suite.assert.Equal(int32(6), counter.Load())
+	suite.assert.GreaterOrEqual(time.Since(start), 250*time.Millisecond)
+	tp.Stop()
+}
+
+func (suite *staticThreadPoolTestSuite) TestScheduleToWorker() {
+	suite.assert = assert.New(suite.T())
+
+	tp := NewStaticThreadPool(4, WithQuiet(), WithWorkerAffinity())
+	suite.assert.NotNil(tp)
+	tp.Start()
+
+	var counter atomic.Int32
+	for i := 0; i < 20; i++ {
+		suite.assert.True(tp.ScheduleToWorker(0, FuncTask(func() { counter.Add(1) })))
+	}
+	for counter.Load() < 20 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	tp.Stop()
+
+	suite.assert.Equal(uint64(20), tp.workerExecuted[0].Load(), "Worker 0 should have run every task")
+	for i := 1; i < 4; i++ {
+		suite.assert.Zero(tp.workerExecuted[i].Load(), "Worker %d should not have run any task", i)
+	}
+
+	suite.assert.False(tp.ScheduleToWorker(4, FuncTask(func() {})), "Index out of range should be rejected")
+	suite.assert.False(NewStaticThreadPool(2).ScheduleToWorker(0, FuncTask(func() {})), "Affinity must be enabled")
+}
+
+func (suite *staticThreadPoolTestSuite) TestCounters() {
+	suite.assert = assert.New(suite.T())
+
+	tp := NewStaticThreadPool(2, WithQuiet())
+	suite.assert.NotNil(tp)
+	tp.Start()
+
+	var counter atomic.Int32
+	for i := 0; i < 7; i++ {
+		tp.Schedule(i%2 == 0, FuncTask(func() { counter.Add(1) }))
+	}
+	for counter.Load() < 7 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	suite.assert.Equal(uint64(7), tp.TotalScheduled())
+	suite.assert.Zero(tp.TotalRejected())
+
+	tp.Stop()
+	suite.assert.False(tp.Schedule(false, FuncTask(func() { counter.Add(1) })))
+	suite.assert.False(tp.Schedule(true, FuncTask(func() { counter.Add(1) })))
+	suite.assert.Equal(uint64(7), tp.TotalScheduled())
+	suite.assert.Equal(uint64(2), tp.TotalRejected(), "Tasks scheduled after Stop should be rejected")
+	suite.assert.Equal(int32(7), counter.Load())
+}
+
+func (suite *staticThreadPoolTestSuite) TestScheduleFunc() {
+	suite.assert = assert.New(suite.T())
+
+	tp := NewStaticThreadPool(2, WithQuiet())
+	suite.assert.NotNil(tp)
+	tp.Start()
+	defer tp.Stop()
+
+	var wg sync.WaitGroup
+	var counter atomic.Int32
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		tp.ScheduleFunc(i%2 == 0, func() {
+			defer wg.Done()
+			counter.Add(1)
+		})
+	}
+	wg.Wait()
+	suite.assert.Equal(int32(4), counter.Load())
+}
+
+func (suite *staticThreadPoolTestSuite) TestScheduleConcurrentWithStop() {
+	suite.assert = assert.New(suite.T())
+
+	tp := NewStaticThreadPool(4, WithQuiet())
+	suite.assert.NotNil(tp)
+	tp.Start()
+
+	var accepted, rejected, executed atomic.Int32
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				if tp.Schedule(i%10 == 0, FuncTask(func() { executed.Add(1) })) {
+					accepted.Add(1)
+				} else {
+					rejected.Add(1)
+				}
+			}
+		}()
+	}
+	time.Sleep(time.Millisecond)
+	suite.assert.NotPanics(tp.Stop)
+	wg.Wait()
+
+	suite.assert.Equal(int32(8*500), accepted.Load()+rejected.Load())
+	suite.assert.Equal(uint64(accepted.Load()), tp.TotalScheduled())
+	suite.assert.Equal(uint64(rejected.Load()), tp.TotalRejected())
+	suite.assert.False(tp.Schedule(false, FuncTask(func() {})), "Stopped pool rejects tasks")
+}
+
+func (suite *staticThreadPoolTestSuite) TestStrictPriority() {
+	suite.assert = assert.New(suite.T())
+
+	// A single worker serves both queues.
+	tp := NewStaticThreadPool(1, WithQuiet(), WithStrictPriority())
+	suite.assert.NotNil(tp)
+	tp.Start()
+	defer tp.Stop()
+
+	var mu sync.Mutex
+	var order []string
+	record := func(name string) Task {
+		return FuncTask(func() {
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+		})
+	}
+
+	// Hold the worker with a long normal task while more work queues up.
+	release := make(chan struct{})
+	started := make(chan struct{})
+	tp.Schedule(false, FuncTask(func() {
+		close(started)
+		<-release
+	}))
+	<-started
+	for i := 0; i < 5; i++ {
+		tp.Schedule(false, record("normal"))
+	}
+	tp.Schedule(true, record("priority"))
+	close(release)
+
+	for {
+		mu.Lock()
+		n := len(order)
+		mu.Unlock()
+		if n == 6 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	suite.assert.Equal("priority", order[0], "Priority task should run as soon as the long task finishes")
+}
+
 func TestThreadPoolSuite(t *testing.T) {
 	suite.Run(t, new(staticThreadPoolTestSuite))
 }
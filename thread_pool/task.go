@@ -1,7 +1,11 @@
 package thread_pool
 
 import (
+	"errors"
+	"fmt"
 	"math/rand"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -10,13 +14,203 @@ type Task interface {
 	Execute()
 }
 
-// PrefetchTask is a concrete implementation of the Task interface.
+// ResultTask is a Task that reports whether its execution succeeded. Pools
+// that track outcomes call Err once Execute has returned.
+type ResultTask interface {
+	Task
+
+	// Err returns the error from the last Execute, or nil if it succeeded.
+	Err() error
+}
+
+// IdentifiableTask is a Task with an ID, e.g. a trace or request ID. Pools
+// include the ID in their logs about the task, so verbose logs can be tied
+// back to the work that caused them. Plain Tasks are logged as before.
+type IdentifiableTask interface {
+	Task
+
+	// ID returns the identifier to log for the task.
+	ID() string
+}
+
+// taskID returns the ID of item, looking through the wrapper pools put around
+// retried tasks, and whether item has one.
+func taskID(item Task) (string, bool) {
+	if retry, ok := item.(*retryTask); ok {
+		item = retry.task
+	}
+	it, ok := item.(IdentifiableTask)
+	if !ok {
+		return "", false
+	}
+	return it.ID(), true
+}
+
+// forTask returns a log suffix naming the task item, or "" if it has no ID.
+func forTask(item Task) string {
+	if id, ok := taskID(item); ok {
+		return fmt.Sprintf(" for task %q", id)
+	}
+	return ""
+}
+
+// FuncTask adapts a plain function to the Task interface, so closures can be
+// scheduled without declaring a type for them.
+type FuncTask func()
+
+// Execute implements the Task interface for FuncTask by calling f.
+func (f FuncTask) Execute() {
+	f()
+}
+
+// ErrPrefetchFailed is reported by a PrefetchTask attempt that is set up to fail.
+var ErrPrefetchFailed = errors.New("prefetch failed")
+
+// PrefetchTask is a concrete implementation of the Task interface. It
+// simulates a block download. One created by newPrefetchTask fails its first
+// failCnt attempts, so it succeeds when retried by a pool created WithRetry;
+// copies of it share the attempts made so far.
 type PrefetchTask struct {
-	failCnt int32
+	failCnt int32          // Attempts that fail before one succeeds.
+	state   *prefetchState // Attempts made so far; nil if every attempt succeeds.
+}
+
+// prefetchState is the outcome of the attempts of a PrefetchTask.
+type prefetchState struct {
+	mu       sync.Mutex
+	attempts int32 // Attempts made so far.
+	err      error // Outcome of the last attempt.
+}
+
+// newPrefetchTask returns a PrefetchTask whose first failCnt attempts fail.
+func newPrefetchTask(failCnt int32) PrefetchTask {
+	return PrefetchTask{failCnt: failCnt, state: &prefetchState{}}
 }
 
 // Execute implements the Task interface for PrefetchTask.
 func (t PrefetchTask) Execute() {
 	// Simulate some work.
 	time.Sleep(time.Duration(rand.Intn(100)) * time.Millisecond)
+
+	if t.state == nil {
+		return
+	}
+	t.state.mu.Lock()
+	defer t.state.mu.Unlock()
+	t.state.attempts++
+	t.state.err = nil
+	if t.state.attempts <= t.failCnt {
+		t.state.err = fmt.Errorf("%w: attempt %d of %d failing", ErrPrefetchFailed, t.state.attempts, t.failCnt)
+	}
+}
+
+// Err implements the ResultTask interface for PrefetchTask.
+func (t PrefetchTask) Err() error {
+	if t.state == nil {
+		return nil
+	}
+	t.state.mu.Lock()
+	defer t.state.mu.Unlock()
+	return t.state.err
+}
+
+// attempts returns how many times t has been executed, or zero if it does not
+// track its attempts.
+func (t PrefetchTask) attempts() int32 {
+	if t.state == nil {
+		return 0
+	}
+	t.state.mu.Lock()
+	defer t.state.mu.Unlock()
+	return t.state.attempts
+}
+
+// RecoverTask wraps a Task and recovers a panic in its Execute, so a task can
+// opt into recovery whichever pool runs it. The recovered value is passed to
+// Handler, if set, and reported by Err as an error wrapping ErrTaskPanicked.
+type RecoverTask struct {
+	Task    Task
+	Handler func(recovered any)
+	err     error // Outcome of the last Execute.
+}
+
+// WrapRecover returns task wrapped in a RecoverTask calling handler, which may
+// be nil.
+func WrapRecover(task Task, handler func(recovered any)) *RecoverTask {
+	return &RecoverTask{Task: task, Handler: handler}
+}
+
+// Execute implements the Task interface for RecoverTask.
+func (t *RecoverTask) Execute() {
+	t.err = nil
+	defer func() {
+		if r := recover(); r != nil {
+			t.err = fmt.Errorf("%w: %v", ErrTaskPanicked, r)
+			if t.Handler != nil {
+				t.Handler(r)
+			}
+		}
+	}()
+
+	t.Task.Execute()
+	if rt, ok := t.Task.(ResultTask); ok {
+		t.err = rt.Err()
+	}
+}
+
+// Err implements the ResultTask interface, reporting the wrapped task's error
+// or the recovered panic.
+func (t *RecoverTask) Err() error {
+	return t.err
+}
+
+// String labels the RecoverTask by the task it wraps.
+func (t *RecoverTask) String() string {
+	return taskLabel(t.Task)
+}
+
+// TimedTask wraps a Task and measures how long its Execute takes, so tasks can
+// be profiled without instrumenting each implementation. Each duration is
+// passed to Record, if set, even if Execute panics.
+type TimedTask struct {
+	Task     Task
+	Record   func(d time.Duration)
+	duration atomic.Int64 // Duration of the last Execute, in nanoseconds.
+}
+
+// WrapTimed returns task wrapped in a TimedTask calling record, which may be
+// nil.
+func WrapTimed(task Task, record func(d time.Duration)) *TimedTask {
+	return &TimedTask{Task: task, Record: record}
+}
+
+// Execute implements the Task interface for TimedTask.
+func (t *TimedTask) Execute() {
+	start := time.Now()
+	defer func() {
+		d := time.Since(start)
+		t.duration.Store(int64(d))
+		if t.Record != nil {
+			t.Record(d)
+		}
+	}()
+	t.Task.Execute()
+}
+
+// Duration returns how long the last Execute took, or zero before the first.
+func (t *TimedTask) Duration() time.Duration {
+	return time.Duration(t.duration.Load())
+}
+
+// Err implements the ResultTask interface, reporting the wrapped task's error.
+func (t *TimedTask) Err() error {
+	if rt, ok := t.Task.(ResultTask); ok {
+		return rt.Err()
+	}
+	return nil
+}
+
+// String labels the TimedTask by the task it wraps.
+func (t *TimedTask) String() string {
+	return taskLabel(t.Task)
 }
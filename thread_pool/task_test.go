@@ -0,0 +1,81 @@
+package thread_pool
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecoverTask(t *testing.T) {
+	var recovered any
+	task := WrapRecover(FuncTask(func() { panic("bad task") }), func(r any) { recovered = r })
+
+	assert.NotPanics(t, task.Execute)
+	assert.Equal(t, "bad task", recovered)
+	assert.ErrorIs(t, task.Err(), ErrTaskPanicked)
+	assert.Contains(t, task.Err().Error(), "bad task")
+
+	// Without a handler the panic is still contained.
+	assert.NotPanics(t, WrapRecover(FuncTask(func() { panic(errors.New("boom")) }), nil).Execute)
+
+	// The error of a wrapped ResultTask is passed through.
+	prefetch := WrapRecover(newPrefetchTask(1), nil)
+	prefetch.Execute()
+	assert.ErrorIs(t, prefetch.Err(), ErrPrefetchFailed)
+	prefetch.Execute()
+	assert.NoError(t, prefetch.Err())
+	assert.Equal(t, "thread_pool.PrefetchTask", prefetch.String())
+}
+
+func TestRecoverTaskInPool(t *testing.T) {
+	var poolPanics int
+	tp := NewStaticThreadPool(1, WithQuiet(), WithPanicHandler(func(any) { poolPanics++ }))
+	tp.Start()
+
+	recovered := make(chan any, 1)
+	assert.True(t, tp.Schedule(false, WrapRecover(FuncTask(func() { panic(42) }), func(r any) { recovered <- r })))
+	assert.Equal(t, 42, <-recovered)
+	tp.Stop()
+	assert.Zero(t, poolPanics, "The pool should not see the recovered panic")
+}
+
+func TestTimedTask(t *testing.T) {
+	const sleep = 50 * time.Millisecond
+	recorded := make(chan time.Duration, 1)
+	task := WrapTimed(FuncTask(func() { time.Sleep(sleep) }), func(d time.Duration) { recorded <- d })
+	assert.Zero(t, task.Duration())
+
+	tp := NewDynamicThreadPool(1, 1, WithQuiet())
+	tp.Start()
+	defer tp.Stop()
+	assert.True(t, tp.Schedule(false, task))
+
+	d := <-recorded
+	assert.GreaterOrEqual(t, d, sleep)
+	assert.Less(t, d, 4*sleep)
+	assert.Equal(t, d, task.Duration())
+	assert.NoError(t, task.Err())
+
+	// The duration is recorded even if the task panics.
+	panicking := WrapTimed(FuncTask(func() { panic("bad task") }), func(d time.Duration) { recorded <- d })
+	assert.Panics(t, panicking.Execute)
+	<-recorded
+	assert.NotZero(t, panicking.Duration())
+}
+
+func TestPrefetchTask(t *testing.T) {
+	// A PrefetchTask value is a Task; copies share the attempts made so far.
+	var task Task = PrefetchTask{}
+	task.Execute()
+	assert.NoError(t, task.(ResultTask).Err())
+
+	prefetch := newPrefetchTask(1)
+	copied := prefetch
+	copied.Execute()
+	assert.ErrorIs(t, prefetch.Err(), ErrPrefetchFailed)
+	prefetch.Execute()
+	assert.NoError(t, copied.Err())
+	assert.Equal(t, int32(2), copied.attempts())
+}
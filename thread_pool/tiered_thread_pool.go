@@ -0,0 +1,242 @@
+package thread_pool
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// TieredThreadPool is a variant of DynamicThreadPool with any number of
+// priority levels instead of two. Level 0 is the highest priority. Every level
+// has its own queue and worker cap, and workers are created on demand. A
+// worker launched for a level also takes tasks from the levels above it,
+// always draining higher levels first, so a busy lower level never holds up
+// a higher one.
+type TieredThreadPool struct {
+	queues  []chan Task     // One queue per level, highest priority first.
+	sems    []chan struct{} // Semaphores limiting the workers of each level.
+	closeCh chan struct{}   // Channel to signal workers to stop.
+
+	wg          sync.WaitGroup // Waits for all active workers to finish.
+	launchMu    sync.Mutex     // Held while adding a worker to wg and while Stop sets isStopped.
+	workerCount atomic.Uint32  // Current total count of active workers.
+	stopOnce    sync.Once      // Ensures Stop logic runs only once.
+	isStopped   atomic.Bool    // Flag to indicate if the pool has been stopped.
+
+	opts poolOptions // Optional behaviour configured at construction.
+}
+
+// NewTieredThreadPool creates a pool with one priority level per entry of
+// workerCaps, highest priority first. workerCaps[i] is the max number of
+// concurrent workers launched for level i and must be > 0. Each level queues
+// up to ten tasks per worker.
+// opts: Optional settings, e.g. WithQuiet().
+func NewTieredThreadPool(workerCaps []uint32, opts ...Option) *TieredThreadPool {
+	options := newPoolOptions(opts)
+	if len(workerCaps) == 0 {
+		options.errorf("TieredThreadPool: at least one level is required\n")
+		return nil
+	}
+	for level, workers := range workerCaps {
+		if workers == 0 {
+			options.errorf("TieredThreadPool: worker cap of level %d cannot be zero\n", level)
+			return nil
+		}
+	}
+
+	if name := options.unsupported(); name != "" {
+		options.errorf("TieredThreadPool: %s is not supported\n", name)
+		return nil
+	}
+
+	options.debugf("TieredThreadPool: Creating with worker caps: %v\n", workerCaps)
+
+	t := &TieredThreadPool{
+		queues:  make([]chan Task, len(workerCaps)),
+		sems:    make([]chan struct{}, len(workerCaps)),
+		closeCh: make(chan struct{}),
+		opts:    options,
+	}
+	for level, workers := range workerCaps {
+		t.queues[level] = make(chan Task, workers*10)
+		t.sems[level] = make(chan struct{}, workers)
+	}
+	return t
+}
+
+// Start prepares the pool to accept tasks. No workers are started initially.
+func (t *TieredThreadPool) Start() {
+	register(t)
+	t.opts.debugf("TieredThreadPool: Started with %d levels. Workers will be created per task.\n", len(t.queues))
+}
+
+// Levels returns the number of priority levels.
+func (t *TieredThreadPool) Levels() int {
+	return len(t.queues)
+}
+
+// Schedule maps urgent to the highest level and everything else to the
+// lowest, matching DynamicThreadPool.Schedule.
+func (t *TieredThreadPool) Schedule(urgent bool, item Task) bool {
+	if urgent {
+		return t.ScheduleLevel(0, item)
+	}
+	return t.ScheduleLevel(len(t.queues)-1, item)
+}
+
+// ScheduleLevel adds a task to the queue of level, 0 being the highest
+// priority, and attempts to launch a worker for it. Returns false if the pool
+// is stopped or level is out of range, true otherwise.
+func (t *TieredThreadPool) ScheduleLevel(level int, item Task) bool {
+	if level < 0 || level >= len(t.queues) {
+		t.opts.errorf("TieredThreadPool: level %d out of range [0, %d)\n", level, len(t.queues))
+		return false
+	}
+	if t.isStopped.Load() {
+		return false
+	}
+
+	select {
+	case t.queues[level] <- item:
+		t.tryLaunchWorker(level)
+		return true
+	case <-t.closeCh:
+		t.opts.errorf("TieredThreadPool: Pool stopped while trying to schedule level %d task\n", level)
+		return false
+	}
+}
+
+// tryLaunchWorker starts a worker for a task queued at level. It prefers a
+// slot of that level, falls back to a free slot of a lower level, whose
+// workers also serve level, and otherwise waits for a slot of level itself,
+// giving up once the pool stops.
+func (t *TieredThreadPool) tryLaunchWorker(level int) {
+	if t.isStopped.Load() { // Check if stopped before trying to launch
+		return
+	}
+
+	for l := level; l < len(t.sems); l++ {
+		select {
+		case t.sems[l] <- struct{}{}:
+			t.launchWorker(l)
+			return
+		default:
+		}
+	}
+
+	select {
+	case t.sems[level] <- struct{}{}:
+		t.launchWorker(level)
+	case <-t.closeCh:
+	}
+}
+
+// launchWorker starts a worker in a slot of level the caller has already
+// acquired. Once the pool is stopped it releases the slot instead, so Stop
+// never waits for a worker that was added after it started waiting.
+func (t *TieredThreadPool) launchWorker(level int) {
+	t.launchMu.Lock()
+	if t.isStopped.Load() {
+		t.launchMu.Unlock()
+		<-t.sems[level]
+		return
+	}
+	t.workerCount.Add(1)
+	t.wg.Add(1)
+	t.launchMu.Unlock()
+	go t.workerTask(level)
+	t.opts.debugf("TieredThreadPool: Launched level %d worker. Active count: %d\n", level, t.workerCount.Load())
+}
+
+// workerTask executes tasks of level and the levels above it until their
+// queues are empty or the pool stops.
+func (t *TieredThreadPool) workerTask(level int) {
+	defer func() {
+		<-t.sems[level]               // Release the semaphore slot
+		t.workerCount.Add(^uint32(0)) // Decrement total worker count
+		t.wg.Done()
+		t.opts.debugf("TieredThreadPool: Level %d worker finished. Active count: %d\n", level, t.workerCount.Load())
+	}()
+
+	for {
+		task, ok := t.nextTask(level)
+		if !ok {
+			return
+		}
+		t.runTask(task)
+	}
+}
+
+// nextTask returns the queued task with the highest priority among levels 0
+// to level. It returns false if those queues are empty or the pool stops.
+func (t *TieredThreadPool) nextTask(level int) (Task, bool) {
+	select {
+	case <-t.closeCh: // Highest priority: Shutdown signal
+		return nil, false
+	default:
+	}
+
+	for l := 0; l <= level; l++ {
+		select {
+		case task, ok := <-t.queues[l]:
+			return task, ok
+		default:
+		}
+	}
+	return nil, false // Nothing left to do
+}
+
+// runTask executes task, recovering a panic so the worker keeps going.
+func (t *TieredThreadPool) runTask(task Task) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.opts.errorf("TieredThreadPool: Recovered panic in task %T: %v\n", task, r)
+			if t.opts.panicHandler != nil {
+				t.opts.panicHandler(r)
+			}
+		}
+	}()
+	task.Execute()
+}
+
+// Stop signals workers to terminate and waits for currently executing workers to finish.
+// Tasks still queued are not executed. The queues and semaphores stay open, so
+// a concurrent Schedule cannot send on a closed channel; it gives up on
+// closeCh instead.
+func (t *TieredThreadPool) Stop() {
+	t.stopOnce.Do(func() {
+		t.opts.debugf("TieredThreadPool: Stopping...\n")
+		t.launchMu.Lock()
+		t.isStopped.Store(true)
+		t.launchMu.Unlock()
+		close(t.closeCh)
+		t.wg.Wait()
+		deregister(t)
+		t.opts.debugf("TieredThreadPool: Pool stopped completely.\n")
+	})
+}
+
+// GetActiveWorkers returns the current total number of worker goroutines executing tasks.
+func (t *TieredThreadPool) GetActiveWorkers() uint32 {
+	return t.workerCount.Load()
+}
+
+// hasStopped implements registeredPool.
+func (t *TieredThreadPool) hasStopped() bool {
+	return t.isStopped.Load()
+}
+
+// info implements registeredPool. The highest level is reported as the
+// priority queue and all other levels as the normal queue.
+func (t *TieredThreadPool) info() PoolInfo {
+	queuedNormal := 0
+	for _, queue := range t.queues[1:] {
+		queuedNormal += len(queue)
+	}
+	return PoolInfo{
+		Name:           t.opts.name,
+		Kind:           "tiered",
+		ActiveWorkers:  t.GetActiveWorkers(),
+		QueuedPriority: len(t.queues[0]),
+		QueuedNormal:   queuedNormal,
+	}
+}
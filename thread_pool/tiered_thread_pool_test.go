@@ -0,0 +1,136 @@
+package thread_pool
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type TieredThreadPoolTestSuite struct {
+	suite.Suite
+	assert *assert.Assertions
+}
+
+func (suite *TieredThreadPoolTestSuite) SetupTest() {
+	suite.assert = assert.New(suite.T())
+}
+
+func (suite *TieredThreadPoolTestSuite) TestCreate() {
+	suite.assert.Nil(NewTieredThreadPool(nil), "Should fail without levels")
+	suite.assert.Nil(NewTieredThreadPool([]uint32{1, 0, 1}), "Should fail with a zero worker cap")
+
+	tp := NewTieredThreadPool([]uint32{2, 2, 4}, WithQuiet())
+	suite.assert.NotNil(tp)
+	suite.assert.Equal(3, tp.Levels())
+	tp.Stop()
+}
+
+func (suite *TieredThreadPoolTestSuite) TestScheduleAllLevels() {
+	tp := NewTieredThreadPool([]uint32{1, 2, 3}, WithQuiet())
+	suite.assert.NotNil(tp)
+	tp.Start()
+
+	var counter atomic.Int32
+	for i := 0; i < 30; i++ {
+		suite.assert.True(tp.ScheduleLevel(i%3, &mockTask{id: i, counter: &counter}))
+	}
+	suite.assert.True(tp.Schedule(true, &mockTask{id: 30, counter: &counter}))
+	suite.assert.True(tp.Schedule(false, &mockTask{id: 31, counter: &counter}))
+	suite.assert.False(tp.ScheduleLevel(3, &mockTask{id: 32, counter: &counter}), "Out of range levels are rejected")
+	suite.assert.False(tp.ScheduleLevel(-1, &mockTask{id: 33, counter: &counter}), "Out of range levels are rejected")
+
+	suite.assert.Eventually(func() bool { return counter.Load() == 32 }, time.Second, 5*time.Millisecond)
+	suite.assert.Eventually(func() bool { return tp.GetActiveWorkers() == 0 }, time.Second, 5*time.Millisecond)
+
+	tp.Stop()
+	suite.assert.False(tp.Schedule(true, &mockTask{id: 34, counter: &counter}), "Stopped pool rejects tasks")
+}
+
+func (suite *TieredThreadPoolTestSuite) TestLowerTierDoesNotStarveHigherTier() {
+	tp := NewTieredThreadPool([]uint32{1, 1, 1}, WithQuiet())
+	suite.assert.NotNil(tp)
+	tp.Start()
+	defer tp.Stop()
+
+	// Keep the background tier saturated for the whole test.
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for p := 0; p < 4; p++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				tp.ScheduleLevel(2, &mockTask{workTime: 5 * time.Millisecond})
+			}
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	var maxLatency time.Duration
+	for i := 0; i < 20; i++ {
+		ran := make(chan time.Time, 1)
+		scheduled := time.Now()
+		suite.assert.True(tp.ScheduleLevel(0, FuncTask(func() { ran <- time.Now() })))
+		select {
+		case at := <-ran:
+			maxLatency = max(maxLatency, at.Sub(scheduled))
+		case <-time.After(time.Second):
+			suite.T().Fatal("Interactive task starved by background load")
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+	close(stop)
+	wg.Wait()
+
+	suite.assert.Less(maxLatency, 50*time.Millisecond, "Interactive tasks should not wait behind background work")
+}
+
+func (suite *TieredThreadPoolTestSuite) TestStopWhileWaitingForWorkerSlot() {
+	tp := NewTieredThreadPool([]uint32{1}, WithQuiet())
+	suite.assert.NotNil(tp)
+	tp.Start()
+
+	blocker := &blockingTask{started: make(chan struct{}), release: make(chan struct{})}
+	suite.assert.True(tp.ScheduleLevel(0, blocker))
+	<-blocker.started
+
+	// The only slot is taken, so these wait for it after queueing.
+	var scheduling sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		scheduling.Add(1)
+		go func() {
+			defer scheduling.Done()
+			tp.ScheduleLevel(0, &mockTask{id: i})
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	stopped := make(chan struct{})
+	go func() {
+		tp.Stop()
+		close(stopped)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(blocker.release)
+
+	select {
+	case <-stopped:
+	case <-time.After(2 * time.Second):
+		suite.T().Fatal("Stop did not return")
+	}
+	scheduling.Wait()
+	suite.assert.Equal(uint32(0), tp.GetActiveWorkers(), "No worker should start after Stop")
+}
+
+func TestTieredThreadPoolSuite(t *testing.T) {
+	suite.Run(t, new(TieredThreadPoolTestSuite))
+}
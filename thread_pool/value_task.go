@@ -0,0 +1,66 @@
+package thread_pool
+
+import (
+	"fmt"
+)
+
+// TaskResult is the outcome of a ValueTask: the value computed by its
+// function, or the zero value and the reason it failed.
+type TaskResult[T any] struct {
+	Value T
+	Err   error
+}
+
+// ValueTask adapts a function computing a value to the Task interface. When
+// executed it sends the outcome to its results channel, so callers can
+// collect the values of many tasks as they complete.
+type ValueTask[T any] struct {
+	fn      func() (T, error)
+	results chan<- TaskResult[T]
+	err     error // Error of the last Execute, for ResultTask.
+}
+
+// NewValueTask returns a task that runs fn and sends its outcome to results.
+// The send blocks until results has room, so results should be buffered or
+// read concurrently.
+func NewValueTask[T any](fn func() (T, error), results chan<- TaskResult[T]) *ValueTask[T] {
+	return &ValueTask[T]{fn: fn, results: results}
+}
+
+// Execute implements the Task interface for ValueTask. A panic in fn is
+// recovered and delivered as an ErrTaskPanicked error with the zero value.
+func (v *ValueTask[T]) Execute() {
+	var result TaskResult[T]
+	defer func() {
+		if r := recover(); r != nil {
+			result = TaskResult[T]{Err: fmt.Errorf("%w: %v", ErrTaskPanicked, r)}
+		}
+		v.err = result.Err
+		v.results <- result
+	}()
+	result.Value, result.Err = v.fn()
+}
+
+// Err implements the ResultTask interface, reporting the error of the last
+// Execute.
+func (v *ValueTask[T]) Err() error {
+	return v.err
+}
+
+// ScheduleValue schedules fn on pool and sends its outcome to results once it
+// completes. It returns false if the pool rejected the task, in which case
+// nothing is sent to results.
+func ScheduleValue[T any](pool *DynamicThreadPool, urgent bool, fn func() (T, error), results chan<- TaskResult[T]) bool {
+	return pool.Schedule(urgent, NewValueTask(fn, results))
+}
+
+// WaitAll receives n results and returns them in the order they arrived. It
+// blocks until all n have been received, so n must only count tasks that were
+// accepted by the pool.
+func WaitAll[T any](results <-chan TaskResult[T], n int) []TaskResult[T] {
+	collected := make([]TaskResult[T], 0, n)
+	for i := 0; i < n; i++ {
+		collected = append(collected, <-results)
+	}
+	return collected
+}
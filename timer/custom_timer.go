@@ -1,41 +1,170 @@
 package timer
 
 import (
+	"context"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
-// CustomTimer represents a custom timer with pause/resume functionality.
+// CustomTimer represents a custom timer with pause/resume functionality. Its
+// methods are safe for concurrent use. The callback runs without the timer's
+// lock held, so it may call back into the timer.
 type CustomTimer struct {
-	duration      time.Duration
-	timer         *time.Timer
-	callback      func()
-	paused        bool
-	lastStartTime time.Time
-	activeElapsed time.Duration
+	mu              sync.Mutex // Guards the fields below except the callbacks, warnLead and warn.
+	duration        time.Duration
+	timer           *time.Timer
+	cancel          chan struct{} // Closed to end the run goroutine waiting on timer.
+	callback        func()
+	elapsedCallback func(time.Duration) // Called instead of callback if set by NewCustomTimerWithElapsed.
+	paused          bool
+	lastStartTime   time.Time
+	activeElapsed   time.Duration
+	adjustment      time.Duration // Deadline shift applied by Adjust to the current run.
+	stopped         bool          // Set by Stop; the timer never fires again.
+	fired           bool          // Whether the callback fired for the current run.
+	repeating       bool          // Set by NewCustomTicker; each fire starts a new run.
+	done            chan struct{} // Closed by Stop to release StopOnContext watchers.
+	fireMu          sync.Mutex    // Serializes ticker callbacks, e.g. after a Reset from a callback.
+
+	warnLead  time.Duration // How long before the callback warn is invoked.
+	warn      func()        // Pre-fire warning set by WithPreFire, may be nil.
+	warnTimer *time.Timer   // Pending warning for the current run.
+	warned    atomic.Bool   // Whether warn already ran for the current run.
+}
+
+// State is a lifecycle phase of a CustomTimer.
+type State int32
+
+const (
+	// StateIdle means the timer has not been started.
+	StateIdle State = iota
+	// StateRunning means the timer is counting down to the callback.
+	StateRunning
+	// StatePaused means the countdown is suspended until Resume.
+	StatePaused
+	// StateFired means the callback has fired. A ticker never reaches it.
+	StateFired
+	// StateStopped means Stop was called; the timer never fires again.
+	StateStopped
+)
+
+// String returns the name of the state.
+func (s State) String() string {
+	switch s {
+	case StateIdle:
+		return "Idle"
+	case StateRunning:
+		return "Running"
+	case StatePaused:
+		return "Paused"
+	case StateFired:
+		return "Fired"
+	case StateStopped:
+		return "Stopped"
+	default:
+		return "Unknown"
+	}
+}
+
+// Option configures optional behaviour of a CustomTimer.
+type Option func(*CustomTimer)
+
+// WithPreFire invokes warn lead before the callback fires. The warning runs on
+// its own goroutine and follows the timer: it is delayed by Pause, moved by
+// Adjust, re-armed by Reset and cancelled by Stop. If less than lead remains
+// when the timer is started or resumed, warn runs right away.
+func WithPreFire(lead time.Duration, warn func()) Option {
+	return func(t *CustomTimer) {
+		t.warnLead = lead
+		t.warn = warn
+	}
 }
 
-// NewCustomTimer creates a new CustomTimer.
-func NewCustomTimer(duration time.Duration, callback func()) *CustomTimer {
-	return &CustomTimer{
+// NewCustomTimer creates a new CustomTimer. It returns nil if duration is zero
+// or negative, since such a timer would fire as soon as it is started.
+func NewCustomTimer(duration time.Duration, callback func(), opts ...Option) *CustomTimer {
+	if duration <= 0 {
+		return nil
+	}
+	t := &CustomTimer{
 		duration: duration,
 		callback: callback,
+		done:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(t)
+		}
 	}
+	return t
 }
 
-// Start starts the timer.
-func (t *CustomTimer) Start() {
-	if t.timer == nil && !t.paused {
-		t.lastStartTime = time.Now()
-		t.timer = time.NewTimer(t.duration)
-		go t.run()
+// NewCustomTimerWithElapsed creates a CustomTimer whose callback is told how
+// long the timer actually ran before firing. The elapsed time is measured from
+// the start of the current run, set by Start, Reset or the previous tick, and
+// excludes time spent paused, so it is directly comparable with duration plus
+// any Adjust. The difference is the scheduling delay of the callback. It
+// returns nil if duration is zero or negative.
+func NewCustomTimerWithElapsed(duration time.Duration, callback func(elapsed time.Duration), opts ...Option) *CustomTimer {
+	t := NewCustomTimer(duration, nil, opts...)
+	if t != nil {
+		t.elapsedCallback = callback
+	}
+	return t
+}
+
+// NewCustomTicker creates a CustomTimer that fires callback every duration
+// until stopped. Each period is a run of its own: Pause and Resume suspend and
+// continue the current period, Reset restarts it, and Adjust only shifts it.
+// Callbacks never overlap: the next period starts once the callback returns,
+// so a slow callback delays the following one. It returns nil if duration is
+// zero or negative.
+func NewCustomTicker(duration time.Duration, callback func(), opts ...Option) *CustomTimer {
+	t := NewCustomTimer(duration, callback, opts...)
+	if t != nil {
+		t.repeating = true
+	}
+	return t
+}
+
+// StartNewCustomTimer creates a CustomTimer like NewCustomTimer and starts it
+// right away. It returns nil if duration is zero or negative. Use
+// NewCustomTimer and Start to configure the timer before it runs.
+func StartNewCustomTimer(duration time.Duration, callback func(), opts ...Option) *CustomTimer {
+	t := NewCustomTimer(duration, callback, opts...)
+	if t != nil {
+		t.Start()
+	}
+	return t
+}
+
+// Start starts the timer. It reports whether it did so: Start does nothing
+// and returns false if the timer has already been started or stopped, so of
+// several concurrent calls exactly one returns true.
+func (t *CustomTimer) Start() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.timer != nil || t.paused || t.stopped {
+		return false
 	}
+	t.lastStartTime = time.Now()
+	t.startRun(t.duration)
+	return true
 }
 
-// Pause pauses the timer.
+// Pause pauses the timer. It has no effect once the timer has fired or been
+// stopped, so a later Resume cannot fire the callback again.
 func (t *CustomTimer) Pause() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.fired || t.stopped {
+		return
+	}
 	if t.timer != nil {
 		if !t.paused {
-			t.timer.Stop()
+			t.endRun()
+			t.disarmWarning()
 			t.activeElapsed += time.Since(t.lastStartTime)
 			t.paused = true
 		}
@@ -44,32 +173,268 @@ func (t *CustomTimer) Pause() {
 
 // Resume resumes the timer.
 func (t *CustomTimer) Resume() {
-	if t.paused {
-		t.paused = false
-		remainingDuration := t.duration - t.activeElapsed
-		if remainingDuration > 0 {
-			t.timer = time.NewTimer(remainingDuration)
-			t.lastStartTime = time.Now()
-			go t.run()
-		} else {
-			t.callback()
-		}
+	t.mu.Lock()
+	if !t.paused || t.stopped {
+		t.mu.Unlock()
+		return
 	}
+	t.paused = false
+	remainingDuration := t.duration + t.adjustment - t.activeElapsed
+	if remainingDuration > 0 {
+		t.lastStartTime = time.Now()
+		t.startRun(remainingDuration)
+		t.mu.Unlock()
+		return
+	}
+	elapsed := t.activeElapsed
+	timer := t.timer
+	t.expire()
+	t.mu.Unlock()
+	t.fire(timer, elapsed)
 }
 
 // Reset resets the timer.
 func (t *CustomTimer) Reset() {
-	if t.timer != nil {
-		t.timer.Stop()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.reset()
+}
+
+// ResetDuration changes the duration of the timer to d and resets it, so the
+// callback fires d from now. Later calls to Reset reuse d. A non-positive d is
+// ignored, as NewCustomTimer would reject it.
+func (t *CustomTimer) ResetDuration(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stopped {
+		return
+	}
+	t.duration = d
+	t.reset()
+}
+
+// reset restarts the timer from zero with the current duration. The caller
+// must hold t.mu.
+func (t *CustomTimer) reset() {
+	if t.stopped {
+		return
 	}
+	t.endRun()
+	t.disarmWarning()
+	t.warned.Store(false)
+	t.fired = false
 	t.paused = false
+	t.activeElapsed = 0
+	t.adjustment = 0
 	t.lastStartTime = time.Now()
-	t.timer = time.NewTimer(t.duration)
-	go t.run()
+	t.startRun(t.duration)
+}
+
+// Adjust moves the deadline of the current run by delta while keeping the time
+// already elapsed. A positive delta extends the timer, a negative one shortens
+// it. If the new remaining time is not positive the callback fires right away,
+// or on Resume when the timer is paused. Reset discards any adjustment.
+func (t *CustomTimer) Adjust(delta time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.adjustment += delta
+	if t.timer == nil || t.paused || t.stopped {
+		return
+	}
+	// Re-arm the pending timer so the goroutine already waiting on it fires at
+	// the new deadline. Stop returns false if the timer has already fired.
+	if t.timer.Stop() {
+		elapsed := t.activeElapsed + time.Since(t.lastStartTime)
+		remaining := t.duration + t.adjustment - elapsed
+		t.timer.Reset(remaining)
+		t.armWarning(remaining)
+	}
+}
+
+// Stop cancels the timer without firing the callback. It is final: Start,
+// Resume, Reset and Adjust have no effect afterwards.
+func (t *CustomTimer) Stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stopped {
+		return
+	}
+	t.stopped = true
+	t.endRun()
+	t.disarmWarning()
+	close(t.done)
+}
+
+// Remaining returns how long is left before the callback fires, not counting
+// time spent paused. It returns the full duration before Start and zero once
+// the callback has fired or the timer has been stopped.
+func (t *CustomTimer) Remaining() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.fired || t.stopped {
+		return 0
+	}
+	remaining := t.duration + t.adjustment - t.activeElapsed
+	if t.timer != nil && !t.paused {
+		remaining -= time.Since(t.lastStartTime)
+	}
+	return max(remaining, 0)
+}
+
+// State returns the current lifecycle phase of the timer. The timer moves to
+// StateFired before the callback is invoked, and back to StateRunning on Reset.
+func (t *CustomTimer) State() State {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch {
+	case t.stopped:
+		return StateStopped
+	case t.fired:
+		return StateFired
+	case t.paused:
+		return StatePaused
+	case t.timer != nil:
+		return StateRunning
+	default:
+		return StateIdle
+	}
+}
+
+// HasFired reports whether the callback has fired since the timer was last
+// started or reset, even if the timer was stopped afterwards. It is always
+// false for a ticker.
+func (t *CustomTimer) HasFired() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.fired
+}
+
+// StopOnContext stops the timer once ctx is done. The goroutine watching ctx
+// exits as soon as either ctx is done or the timer is stopped; a timer that
+// fires keeps it until one of those happens.
+func (t *CustomTimer) StopOnContext(ctx context.Context) {
+	go func() {
+		select {
+		case <-ctx.Done():
+			t.Stop()
+		case <-t.done:
+		}
+	}()
+}
+
+// startRun arms a new timer for remaining and starts the run goroutine that
+// waits on it. The caller must hold t.mu.
+func (t *CustomTimer) startRun(remaining time.Duration) {
+	t.timer = time.NewTimer(remaining)
+	t.cancel = make(chan struct{})
+	t.armWarning(remaining)
+	go t.run(t.timer, t.cancel)
+}
+
+// endRun stops the current timer and ends its run goroutine, if any. The
+// timer itself is kept so Start still sees the timer as started. The caller
+// must hold t.mu.
+func (t *CustomTimer) endRun() {
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+	if t.cancel != nil {
+		close(t.cancel)
+		t.cancel = nil
+	}
 }
 
 // run is a helper function that waits for the timer to expire and calls the callback.
-func (t *CustomTimer) run() {
-	<-t.timer.C
-	t.callback()
+// It returns without calling back once cancel is closed. The callback is also
+// skipped if timer was replaced, paused or stopped while it fired, and is
+// invoked after the lock has been released.
+func (t *CustomTimer) run(timer *time.Timer, cancel <-chan struct{}) {
+	select {
+	case <-timer.C:
+	case <-cancel:
+		return
+	}
+	t.mu.Lock()
+	current := t.timer == timer && !t.paused && !t.stopped
+	var elapsed time.Duration
+	if current {
+		elapsed = t.activeElapsed + time.Since(t.lastStartTime)
+		t.expire()
+	}
+	t.mu.Unlock()
+	if current {
+		t.fire(timer, elapsed)
+	}
+}
+
+// fire invokes the callback of t for the run of timer, passing elapsed to a
+// callback set by NewCustomTimerWithElapsed. A ticker then starts its next
+// period.
+func (t *CustomTimer) fire(timer *time.Timer, elapsed time.Duration) {
+	if t.repeating {
+		t.fireMu.Lock()
+		defer t.fireMu.Unlock()
+	}
+	if t.elapsedCallback != nil {
+		t.elapsedCallback(elapsed)
+	} else {
+		t.callback()
+	}
+	if t.repeating {
+		t.nextPeriod(timer)
+	}
+}
+
+// expire records that the current run is due. A plain timer is marked fired;
+// a ticker clears the state of the period, whose successor is started by
+// nextPeriod once the callback returns. The caller must hold t.mu.
+func (t *CustomTimer) expire() {
+	if !t.repeating {
+		t.fired = true
+		return
+	}
+	t.warned.Store(false)
+	t.activeElapsed = 0
+	t.adjustment = 0
+	t.lastStartTime = time.Now()
+}
+
+// nextPeriod starts the period following the run of timer, so the callback
+// fires again duration after it returned. It does nothing if the ticker was
+// paused, reset or stopped during the callback.
+func (t *CustomTimer) nextPeriod(timer *time.Timer) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.timer != timer || t.paused || t.stopped {
+		return
+	}
+	t.lastStartTime = time.Now()
+	t.startRun(t.duration + t.adjustment)
+}
+
+// armWarning schedules the pre-fire warning for a run with remaining time left
+// before the callback. It does nothing without WithPreFire or once the warning
+// has already run for the current run. The caller must hold t.mu.
+func (t *CustomTimer) armWarning(remaining time.Duration) {
+	if t.warn == nil || t.warned.Load() {
+		return
+	}
+	t.disarmWarning()
+	t.warnTimer = time.AfterFunc(max(remaining-t.warnLead, 0), func() {
+		if t.warned.CompareAndSwap(false, true) {
+			t.warn()
+		}
+	})
+}
+
+// disarmWarning cancels the pending pre-fire warning, if any. The caller must
+// hold t.mu.
+func (t *CustomTimer) disarmWarning() {
+	if t.warnTimer != nil {
+		t.warnTimer.Stop()
+		t.warnTimer = nil
+	}
 }